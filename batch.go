@@ -0,0 +1,76 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+)
+
+type BatchLoaderFnc[M any] func(ctx context.Context, keys []string) (map[string]M, error)
+
+// MGetMap returns the values found for keys along with the keys that
+// missed. Other read errors, such as values sealed with an unknown key,
+// are returned rather than counted as misses so they are not overwritten.
+func (s *Schema[M]) MGetMap(keys ...string) (_ map[string]M, _ []string, err error) {
+	s, end := s.trace("mget", keys...)
+	defer end(&err)
+
+	vals, err := s.fetch(keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hits := make(map[string]M, len(keys))
+	misses := []string{}
+	for i, key := range keys {
+		val, stale, err := s.read(key, vals[i])
+		if errors.Is(err, ErrKeyNotFound) {
+			misses = append(misses, key)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if stale && s.loader != nil {
+			s.revalidate(key, s.loader)
+		}
+		hits[key] = val
+	}
+	return hits, misses, nil
+}
+
+// MGetOrLoad returns the values for keys, fetching every miss with one
+// loader call and writing the loaded values back. Keys the loader does not
+// return are left out of the result.
+func (s *Schema[M]) MGetOrLoad(keys []string, loader BatchLoaderFnc[M], opts ...StoreOptions) (_ map[string]M, err error) {
+	s, end := s.trace("mget_or_load", keys...)
+	defer end(&err)
+
+	hits, misses, err := s.MGetMap(keys...)
+	if err != nil {
+		return nil, err
+	}
+	if len(misses) == 0 {
+		return hits, nil
+	}
+
+	loaded, err := loader(s.ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	params := make([]Params[M], 0, len(loaded))
+	for _, key := range misses {
+		val, ok := loaded[key]
+		if !ok {
+			continue
+		}
+		hits[key] = val
+		params = append(params, Params[M]{Key: key, Value: val, Options: opt})
+	}
+
+	return hits, s.MSet(params...)
+}
@@ -0,0 +1,128 @@
+package cacher_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+type countingStore struct {
+	*cacher.Memory
+	mgets, msets, mdeletes int
+}
+
+func (c *countingStore) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	c.mgets++
+	return c.Memory.MGet(ctx, keys...)
+}
+
+func (c *countingStore) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	c.msets++
+	return c.Memory.MSet(ctx, params...)
+}
+
+func (c *countingStore) MDelete(ctx context.Context, keys ...string) error {
+	c.mdeletes++
+	return c.Memory.MDelete(ctx, keys...)
+}
+
+func Test_BatchStore(t *testing.T) {
+	store := &countingStore{
+		Memory: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}).(*cacher.Memory),
+	}
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Namespace: "users",
+	})
+
+	err := cache.MSet(cacher.Params[string]{
+		Key:   "1",
+		Value: "John",
+	}, cacher.Params[string]{
+		Key:   "2",
+		Value: "Jane",
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1, store.msets)
+
+	list, err := cache.MGet("1", "2")
+	require.Nil(t, err)
+	require.Equal(t, []string{"John", "Jane"}, list)
+	require.Equal(t, 1, store.mgets)
+
+	_, err = cache.MGet("1", "3")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	err = cache.MDelete("1", "2")
+	require.Nil(t, err)
+	require.Equal(t, 1, store.mdeletes)
+
+	_, err = cache.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+func Test_MGetMap(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+	})
+
+	require.Nil(t, cache.Set("1", "John"))
+	require.Nil(t, cache.Set("3", "Jack"))
+
+	hits, misses, err := cache.MGetMap("1", "2", "3", "4")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"1": "John", "3": "Jack"}, hits)
+	require.Equal(t, []string{"2", "4"}, misses)
+
+	var loaded []string
+	data, err := cache.MGetOrLoad([]string{"1", "2", "3", "4"}, func(ctx context.Context, keys []string) (map[string]string, error) {
+		loaded = keys
+		return map[string]string{"2": "Jane"}, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"2", "4"}, loaded)
+	require.Equal(t, map[string]string{"1": "John", "2": "Jane", "3": "Jack"}, data)
+
+	val, err := cache.Get("2")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", val)
+}
+
+func Test_MGetOrLoadUnknownKey(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	keyring := cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))
+	keyring.Rotate("k2", bytes.Repeat([]byte{2}, 32))
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Encryptor: cacher.NewAESGCM(keyring),
+	})
+	require.Nil(t, cache.Set("1", "John"))
+
+	// an instance without k2 neither misses nor reloads the value
+	stale := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Encryptor: cacher.NewAESGCM(cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))),
+	})
+	_, _, err := stale.MGetMap("1")
+	require.ErrorIs(t, err, cacher.ErrUnknownKey)
+
+	loads := 0
+	_, err = stale.MGetOrLoad([]string{"1"}, func(ctx context.Context, keys []string) (map[string]string, error) {
+		loads++
+		return map[string]string{"1": "Jane"}, nil
+	})
+	require.ErrorIs(t, err, cacher.ErrUnknownKey)
+	require.Zero(t, loads)
+
+	val, err := cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", val)
+}
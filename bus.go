@@ -0,0 +1,160 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+type InvalidationKind string
+
+const (
+	// InvalidateKeys drops the store keys in Keys.
+	InvalidateKeys InvalidationKind = "keys"
+	// InvalidatePrefix drops every key starting with Keys[0].
+	InvalidatePrefix InvalidationKind = "prefix"
+	// InvalidateTagged drops the values tagged with Keys.
+	InvalidateTagged InvalidationKind = "tags"
+	// InvalidateAll drops everything.
+	InvalidateAll InvalidationKind = "all"
+)
+
+// ErrSharedStore is returned by SubscribeStore for stores that may be
+// shared between instances, where dropping a key would drop it for all.
+var ErrSharedStore = errors.New("store may be shared, subscribe a TieredStore or a Memory store")
+
+// Invalidation tells other instances to drop values changed by Origin.
+type Invalidation struct {
+	Origin string           `json:"origin"`
+	Kind   InvalidationKind `json:"kind"`
+	Keys   []string         `json:"keys,omitempty"`
+}
+
+// InvalidationBus carries invalidations between instances sharing a store.
+// Subscribers never receive the events published under their own Origin.
+type InvalidationBus interface {
+	// Origin identifies this instance on the bus.
+	Origin() string
+	Publish(ctx context.Context, event Invalidation) error
+	Subscribe(handler func(event Invalidation)) (cancel func(), err error)
+}
+
+// SubscribeStore drops from store what other instances invalidate on bus.
+// A TieredStore only drops from its L1, since its L2 is shared. Other
+// stores must be a Memory store, local to the instance, or SubscribeStore
+// returns ErrSharedStore. Tagged values are dropped by clearing store,
+// which may hold untagged copies.
+func SubscribeStore(bus InvalidationBus, store Store) (func(), error) {
+	if tiered, ok := As[*TieredStore](store); ok {
+		store = tiered.l1
+	} else if _, ok := As[*Memory](store); !ok {
+		return nil, ErrSharedStore
+	}
+	return bus.Subscribe(func(event Invalidation) {
+		ctx := context.Background()
+		switch event.Kind {
+		case InvalidateKeys:
+			for _, key := range event.Keys {
+				store.Delete(ctx, key)
+			}
+		case InvalidatePrefix:
+			prefixes, ok := As[PrefixStore](store)
+			if ok && len(event.Keys) == 1 {
+				prefixes.ClearPrefix(ctx, event.Keys[0])
+				return
+			}
+			store.Clear(ctx)
+		default:
+			store.Clear(ctx)
+		}
+	})
+}
+
+// LocalHub connects in-process buses, standing in for a broker between
+// instances in tests and single binaries.
+type LocalHub struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]localSub
+}
+
+type localSub struct {
+	origin  string
+	handler func(event Invalidation)
+}
+
+func NewLocalHub() *LocalHub {
+	return &LocalHub{subs: make(map[int]localSub)}
+}
+
+// Bus returns a bus joined to the hub with an origin of its own. Events
+// are delivered synchronously.
+func (h *LocalHub) Bus() InvalidationBus {
+	origin, err := newID()
+	if err != nil {
+		panic(err)
+	}
+	return &localBus{hub: h, origin: origin}
+}
+
+type localBus struct {
+	hub    *LocalHub
+	origin string
+}
+
+func (b *localBus) Origin() string {
+	return b.origin
+}
+
+func (b *localBus) Publish(ctx context.Context, event Invalidation) error {
+	b.hub.mu.RLock()
+	subs := make([]localSub, 0, len(b.hub.subs))
+	for _, sub := range b.hub.subs {
+		subs = append(subs, sub)
+	}
+	b.hub.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.origin != event.Origin {
+			sub.handler(event)
+		}
+	}
+	return nil
+}
+
+func (b *localBus) Subscribe(handler func(event Invalidation)) (func(), error) {
+	b.hub.mu.Lock()
+	id := b.hub.next
+	b.hub.next++
+	b.hub.subs[id] = localSub{origin: b.origin, handler: handler}
+	b.hub.mu.Unlock()
+
+	return func() {
+		b.hub.mu.Lock()
+		delete(b.hub.subs, id)
+		b.hub.mu.Unlock()
+	}, nil
+}
+
+// publish tells other instances to drop what an operation changed. The
+// operation has already succeeded, so a failed publish is only reported
+// to the Error hooks.
+func (s *Schema[M]) publish(kind InvalidationKind, keys ...string) {
+	if s.Bus == nil {
+		return
+	}
+	err := s.Bus.Publish(s.ctx, Invalidation{
+		Origin: s.Bus.Origin(),
+		Kind:   kind,
+		Keys:   keys,
+	})
+	if err == nil {
+		return
+	}
+	key := ""
+	if kind == InvalidateKeys && len(keys) == 1 {
+		key = strings.TrimPrefix(keys[0], s.prefix())
+	}
+	s.fail(key, ReasonBus, err)
+}
@@ -0,0 +1,111 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_InvalidationBus(t *testing.T) {
+	ctx := context.Background()
+	shared := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Hour})
+	hub := cacher.NewLocalHub()
+
+	type pod struct {
+		store  cacher.Store
+		schema *cacher.Schema[string]
+	}
+	newPod := func() pod {
+		store := cacher.NewTiered(cacher.TieredOptions{L2: shared})
+		bus := hub.Bus()
+		cancel, err := cacher.SubscribeStore(bus, store)
+		require.Nil(t, err)
+		t.Cleanup(cancel)
+		return pod{
+			store: store,
+			schema: cacher.NewSchema[string](cacher.Config{
+				Store:     store,
+				Namespace: "users",
+				Bus:       bus,
+			}),
+		}
+	}
+	pod1, pod2 := newPod(), newPod()
+
+	require.Nil(t, pod1.schema.Set("1", "John"))
+	data, err := pod2.schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+
+	// pod2 drops its L1 copy when pod1 writes
+	require.Nil(t, pod1.schema.Set("1", "Jane"))
+	data, err = pod2.schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", data)
+
+	// pod1 ignores its own events and keeps its L1 copy
+	l1 := pod1.store.(*cacher.TieredStore).L1()
+	_, err = l1.Get(ctx, "users:1")
+	require.Nil(t, err)
+
+	require.Nil(t, pod1.schema.Delete("1"))
+	_, err = pod2.store.(*cacher.TieredStore).L1().Get(ctx, "users:1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	require.Nil(t, pod2.schema.Set("2", "John"))
+	_, err = pod1.schema.Get("2")
+	require.Nil(t, err)
+	require.Nil(t, pod2.schema.Clear())
+	_, err = l1.Get(ctx, "users:2")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+type failingBus struct {
+	cacher.InvalidationBus
+	err error
+}
+
+func (b failingBus) Publish(ctx context.Context, event cacher.Invalidation) error {
+	return b.err
+}
+
+func Test_InvalidationBusFailures(t *testing.T) {
+	hub := cacher.NewLocalHub()
+
+	// a shared store would lose its keys for every instance
+	shared := &failingStore{Store: cacher.NewInMemory(cacher.StoreOptions{})}
+	_, err := cacher.SubscribeStore(hub.Bus(), shared)
+	require.ErrorIs(t, err, cacher.ErrSharedStore)
+
+	local := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Hour})
+	cancel, err := cacher.SubscribeStore(hub.Bus(), local)
+	require.Nil(t, err)
+	cancel()
+
+	// the write went through, so a failed publish is only reported
+	down := errors.New("bus down")
+	failures := make(chan cacher.Event, 1)
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store:     local,
+		Namespace: "users",
+		Bus:       failingBus{InvalidationBus: hub.Bus(), err: down},
+		Hooks: []cacher.Hook{
+			{Key: cacher.Error, Fnc: func(key string, data interface{}) {
+				failures <- data.(cacher.Event)
+			}},
+		},
+	})
+	require.Nil(t, schema.Set("1", "John"))
+	event := <-failures
+	require.Equal(t, "users:1", event.Key)
+	require.Equal(t, cacher.ReasonBus, event.Reason)
+	require.ErrorIs(t, event.Err, down)
+
+	data, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+}
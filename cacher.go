@@ -2,7 +2,13 @@ package cacher
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/tinh-tinh/tinhtinh/v2/common/compress"
 )
@@ -15,20 +21,74 @@ type Params[M any] struct {
 
 type Schema[M any] struct {
 	Config
-	ctx context.Context
+	ctx    context.Context
+	loads  *flight[M]
+	loader KeyLoaderFnc[M]
+	// plain writes values without an entry header, for counters the
+	// store increments in place.
+	plain bool
+	// unwatch stops forwarding store events, see Close.
+	unwatch func()
+	typed   typedHooks[M]
+	// traced is the state of the span of the running operation.
+	traced *spanState
 }
 
 type Config struct {
-	Store       Store
+	Store Store
+	// Middlewares wrap Store for the schema, the first one outermost.
+	Middlewares []StoreMiddleware
+	// Codec encodes values. When nil, []byte and string values are stored
+	// as they are and others as JSON, or gob when compressing.
+	Codec       Codec
 	CompressAlg compress.Alg
-	Hooks       []Hook
-	Namespace   string
+	// Encryptor seals values after encoding and compression, reads then
+	// reject values it did not seal.
+	Encryptor Encryptor
+	// Bus publishes the keys Set, Delete and Clear change so other
+	// instances drop their local copies.
+	Bus       InvalidationBus
+	Hooks     []Hook
+	Namespace string
+	// Metrics counts the hits, misses, errors, latencies and value sizes
+	// of the schema, see MetricsHandler.
+	Metrics *Metrics
+	// Tracer opens a span for every operation.
+	Tracer Tracer
+	// TraceKey turns keys into the cache.key span attribute, nil records
+	// a hash of the key and an empty result leaves it out.
+	TraceKey func(key string) string
+	// MaxLoads caps how many GetOrLoad loaders may run at once, 0 means no limit.
+	MaxLoads int
+	// Ttl is the lifetime of values written without a StoreOptions.Ttl,
+	// 0 leaves it to the store.
+	Ttl time.Duration
+	// StaleTtl keeps serving a value for this long after its ttl runs out
+	// while the loader refreshes it in the background.
+	StaleTtl time.Duration
+	// Recompute expires values early at random so GetOrLoad refreshes them
+	// before they all expire together.
+	Recompute *XFetch
+	// watched subscribes the schemas injected from a registered config
+	// to the store events once, see InjectSchema.
+	watched *sync.Once
 }
 
 func NewSchema[M any](config Config) *Schema[M] {
+	s := newSchema[M](config)
+	s.unwatch = s.Config.watch()
+	return s
+}
+
+func newSchema[M any](config Config) *Schema[M] {
+	if len(config.Middlewares) > 0 {
+		config.Store = Chain(config.Store, config.Middlewares...)
+		config.Middlewares = nil
+	}
 	return &Schema[M]{
 		Config: config,
 		ctx:    context.Background(),
+		loads:  newFlight[M](config.MaxLoads),
 	}
 }
 
@@ -44,84 +104,428 @@ func (s *Schema[M]) GetHooks() []Hook {
 	return s.Hooks
 }
 
-func (s *Schema[M]) Get(key string) (M, error) {
-	HandlerBeforeGet(*s, key)
+func (s *Schema[M]) Get(key string) (_ M, err error) {
+	s, end := s.trace("get", key)
+	defer end(&err)
+
+	schema, stale, err := s.get(key)
+	if err != nil {
+		return *new(M), err
+	}
+	if stale && s.loader != nil {
+		s.revalidate(key, s.loader)
+	}
+	return schema, nil
+}
+
+func (s *Schema[M]) get(key string) (M, bool, error) {
+	defer s.measure("get", time.Now())
+	if err := HandlerBeforeGet(*s, key); err != nil {
+		return *new(M), false, err
+	}
 
 	val, err := s.Store.Get(s.ctx, s.generateKey(key))
+	if errors.Is(err, ErrKeyNotFound) {
+		s.emit(Miss, key, ReasonNotFound, nil)
+		return *new(M), false, err
+	}
+	if err != nil {
+		return *new(M), false, s.fail(key, ReasonStore, err)
+	}
+	return s.read(key, val)
+}
+
+// read decodes the raw value stored for key and reports whether it is
+// stale. Corrupt values are evicted and read as misses, values sealed with
+// an unknown key are returned as ErrUnknownKey and kept.
+func (s *Schema[M]) read(key string, val []byte) (M, bool, error) {
+	return s.readWith(key, val, s.Recompute)
+}
+
+// readWith reads like read, expiring values early with recompute when it
+// is not nil.
+func (s *Schema[M]) readWith(key string, val []byte, recompute *XFetch) (M, bool, error) {
+	if val == nil {
+		s.emit(Miss, key, ReasonNotFound, nil)
+		return *new(M), false, ErrKeyNotFound
+	}
+	s.measureSize("get", len(val))
+
+	e, err := decodeEntry(val)
+	if err == nil {
+		err = s.open(key, &e)
+	}
+	if errors.Is(err, ErrCorrupt) {
+		s.Store.Delete(s.ctx, s.generateKey(key))
+		s.emit(Evict, key, ReasonCorrupt, err)
+		s.emit(Miss, key, ReasonCorrupt, err)
+		return *new(M), false, ErrKeyNotFound
+	}
 	if err != nil {
+		return *new(M), false, s.fail(key, ReasonCodec, err)
+	}
+
+	now := time.Now()
+	if e.dead(now) {
+		s.emit(Miss, key, ReasonExpired, nil)
+		return *new(M), false, ErrKeyNotFound
+	}
+	stale := e.stale(now)
+	if !stale && recompute != nil && recompute.expired(e, now) {
+		if s.StaleTtl <= 0 {
+			s.emit(Miss, key, ReasonRecompute, nil)
+			return *new(M), false, ErrKeyNotFound
+		}
+		stale = true
+	}
+
+	schema, err := s.decode(e)
+	if err != nil {
+		return *new(M), false, s.fail(key, ReasonCodec, err)
+	}
+	s.emit(Hit, key, "", nil)
+
+	if err := HandlerAfterGet(*s, key, schema); err != nil {
+		return *new(M), false, err
+	}
+	return schema, stale, nil
+}
+
+// open unseals e in place. With an Encryptor, values that were never
+// sealed or fail to authenticate are corrupt, while values sealed with a
+// key missing from the keyring are left for the instances that have it.
+func (s *Schema[M]) open(key string, e *entry) error {
+	if s.Encryptor == nil {
+		if e.sealed {
+			return ErrSealed
+		}
+		return nil
+	}
+	if !e.sealed {
+		return ErrCorrupt
+	}
+	val, err := s.Encryptor.Open(e.value, s.aad(key, *e))
+	if err != nil {
+		return err
+	}
+	e.value = val
+	return nil
+}
+
+// aad binds a sealed value to its store key and to the header of e, so
+// its expiry, codec and flags cannot be changed without breaking it.
+func (s *Schema[M]) aad(key string, e entry) []byte {
+	storeKey := s.generateKey(key)
+	aad := binary.BigEndian.AppendUint32(nil, uint32(len(storeKey)))
+	aad = append(aad, storeKey...)
+	return append(aad, e.header()...)
+}
+
+// decode decodes e with the codec and compression its header names.
+func (s *Schema[M]) decode(e entry) (M, error) {
+	if e.legacy {
+		return s.decodeLegacy(e.value)
+	}
+
+	codec := s.Codec
+	if codec == nil || codec.Name() != e.codec {
+		var ok bool
+		codec, ok = LookupCodec(e.codec)
+		if !ok {
+			return *new(M), fmt.Errorf("unknown codec %q", e.codec)
+		}
+	}
+	val := e.value
+	if e.alg != "" {
+		var err error
+		val, err = compress.Decode(val, e.alg)
+		if err != nil {
+			return *new(M), err
+		}
+	}
+
+	var schema M
+	if err := codec.Unmarshal(val, &schema); err != nil {
 		return *new(M), err
 	}
+	return schema, nil
+}
 
+// decodeLegacy decodes values written without a header, guessing their
+// format from the schema config.
+func (s *Schema[M]) decodeLegacy(val []byte) (M, error) {
 	var schema M
-	err = json.Unmarshal(val, &schema)
+	if s.Codec != nil {
+		if s.CompressAlg != "" {
+			raw, err := compress.Decode(val, s.CompressAlg)
+			if err != nil {
+				return *new(M), err
+			}
+			val = raw
+		}
+		if err := s.Codec.Unmarshal(val, &schema); err != nil {
+			return *new(M), err
+		}
+		return schema, nil
+	}
+
+	err := json.Unmarshal(val, &schema)
 	if err != nil {
 		if s.CompressAlg != "" {
 			return compress.DecodeMarshall[M](val, s.CompressAlg)
 		}
 		return *new(M), err
 	}
-
-	HandlerAfterGet(*s, key, schema)
 	return schema, nil
 }
 
-func (s *Schema[M]) MGet(keys ...string) ([]M, error) {
-	var schemas []M
-	for _, key := range keys {
-		val, err := s.Get(key)
+func (s *Schema[M]) MGet(keys ...string) (_ []M, err error) {
+	s, end := s.trace("mget", keys...)
+	defer end(&err)
+	defer s.measure("mget", time.Now())
+	vals, err := s.fetch(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]M, 0, len(keys))
+	for i, key := range keys {
+		val, stale, err := s.read(key, vals[i])
 		if err != nil {
 			return nil, err
 		}
+		if stale && s.loader != nil {
+			s.revalidate(key, s.loader)
+		}
 		schemas = append(schemas, val)
 	}
 
 	return schemas, nil
 }
 
+// fetch reads the raw values of keys, nil for misses, in one round trip
+// when the store supports it.
+func (s *Schema[M]) fetch(keys []string) ([][]byte, error) {
+	for _, key := range keys {
+		if err := HandlerBeforeGet(*s, key); err != nil {
+			return nil, err
+		}
+	}
+
+	vals, err := mget(s.ctx, s.Store, s.generateKeys(keys))
+	if err != nil {
+		return nil, s.fail("", ReasonStore, err)
+	}
+	return vals, nil
+}
+
 func (s *Schema[M]) Set(key string, data M, opts ...StoreOptions) (err error) {
-	HandlerBeforeSet(*s, key, data)
+	s, end := s.trace("set", key)
+	defer end(&err)
+	return s.set(key, data, 0, opts...)
+}
+
+// set writes data, recording delta as the time it took to compute.
+func (s *Schema[M]) set(key string, data M, delta time.Duration, opts ...StoreOptions) error {
+	defer s.measure("set", time.Now())
+	if err := s.beforeSet(key, &data); err != nil {
+		return err
+	}
 
-	var value []byte
+	value, opts, err := s.prepare(key, data, delta, opts)
+	if err != nil {
+		return s.fail(key, ReasonCodec, err)
+	}
+	s.measureSize("set", len(value))
+	err = s.Store.Set(s.ctx, s.generateKey(key), value, opts...)
+	if err != nil {
+		return s.fail(key, ReasonStore, err)
+	}
+	s.publish(InvalidateKeys, s.generateKey(key))
+
+	return HandlerAfterSet(*s, key, data)
+}
+
+// prepare encodes data into the value and options handed to the store.
+func (s *Schema[M]) prepare(key string, data M, delta time.Duration, opts []StoreOptions) ([]byte, []StoreOptions, error) {
+	codec := s.codec()
+	value, err := codec.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
 	if s.CompressAlg != "" {
-		value, err = compress.Encode(data, s.CompressAlg)
+		value, err = compressBytes(value, s.CompressAlg)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-	} else {
-		value, err = json.Marshal(data)
+	}
+
+	e, opts := s.wrap(delta, opts)
+	if s.plain {
+		return value, opts, nil
+	}
+	if len(codec.Name()) > 255 {
+		return nil, nil, fmt.Errorf("codec name %q is too long", codec.Name())
+	}
+	e.codec = codec.Name()
+	e.alg = s.CompressAlg
+	if s.Encryptor != nil {
+		e.sealed = true
+		value, err = s.Encryptor.Seal(value, s.aad(key, e))
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
+	e.value = value
+	return e.encode(), opts, nil
+}
 
-	err = s.Store.Set(s.ctx, s.generateKey(key), value, opts...)
-	if err != nil {
-		return err
+// codec returns the codec values are written with. Without one, []byte
+// and string values are stored as they are, and other values as JSON, or
+// gob when compressing as earlier versions did.
+func (s *Schema[M]) codec() Codec {
+	if s.Codec != nil {
+		return s.Codec
+	}
+	switch any(*new(M)).(type) {
+	case []byte, string:
+		return Raw
+	}
+	if s.CompressAlg != "" {
+		return Gob
 	}
+	return JSON
+}
 
-	HandlerAfterSet(*s, key, data)
-	return nil
+// wrap applies the schema ttl to opts and, when a read policy needs it,
+// records the expiry and compute time in the entry header.
+func (s *Schema[M]) wrap(delta time.Duration, opts []StoreOptions) (entry, []StoreOptions) {
+	var opt StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Ttl <= 0 {
+		opt.Ttl = s.Ttl
+	}
+
+	now := time.Now()
+	e := entry{created: now.UnixNano(), delta: int64(delta)}
+	if opt.Ttl <= 0 {
+		return e, opts
+	}
+	if s.StaleTtl <= 0 && s.Recompute == nil {
+		return e, []StoreOptions{opt}
+	}
+
+	e.expiry = now.Add(opt.Ttl).UnixNano()
+	e.deadline = now.Add(opt.Ttl + s.StaleTtl).UnixNano()
+	opt.Ttl += s.StaleTtl
+	return e, []StoreOptions{opt}
 }
 
-func (s *Schema[M]) MSet(params ...Params[M]) error {
-	for _, param := range params {
-		if err := s.Set(param.Key, param.Value, param.Options); err != nil {
+func (s *Schema[M]) MSet(params ...Params[M]) (err error) {
+	keys := make([]string, len(params))
+	for i, param := range params {
+		keys[i] = param.Key
+	}
+	s, end := s.trace("mset", keys...)
+	defer end(&err)
+	store, ok := As[BatchStore](s.Store)
+	if !ok {
+		for _, param := range params {
+			if err := s.Set(param.Key, param.Value, param.Options); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	defer s.measure("mset", time.Now())
+	params = slices.Clone(params)
+	items := make([]StoreParams, 0, len(params))
+	for i, param := range params {
+		if err := s.beforeSet(param.Key, &params[i].Value); err != nil {
 			return err
 		}
+		param = params[i]
+
+		value, opts, err := s.prepare(param.Key, param.Value, 0, []StoreOptions{param.Options})
+		if err != nil {
+			return s.fail(param.Key, ReasonCodec, err)
+		}
+		s.measureSize("set", len(value))
+		items = append(items, StoreParams{
+			Key:     s.generateKey(param.Key),
+			Value:   value,
+			Options: opts[0],
+		})
 	}
 
+	err = store.MSet(s.ctx, items...)
+	if err != nil {
+		return s.fail("", ReasonStore, err)
+	}
+	storeKeys := make([]string, len(items))
+	for i, item := range items {
+		storeKeys[i] = item.Key
+	}
+	s.publish(InvalidateKeys, storeKeys...)
+
+	for _, param := range params {
+		if err := HandlerAfterSet(*s, param.Key, param.Value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *Schema[M]) Delete(key string) error {
-	HandlerBeforeDelete(*s, key)
+func (s *Schema[M]) Delete(key string) (err error) {
+	s, end := s.trace("delete", key)
+	defer end(&err)
+	defer s.measure("delete", time.Now())
+	if err := HandlerBeforeDelete(*s, key); err != nil {
+		return err
+	}
 
-	err := s.Store.Delete(s.ctx, s.generateKey(key))
+	err = s.Store.Delete(s.ctx, s.generateKey(key))
 	if err != nil {
-		return err
+		return s.fail(key, ReasonStore, err)
 	}
+	s.publish(InvalidateKeys, s.generateKey(key))
 
-	HandlerAfterDelete(*s, key)
+	return HandlerAfterDelete(*s, key)
+}
+
+func (s *Schema[M]) MDelete(keys ...string) (err error) {
+	s, end := s.trace("mdelete", keys...)
+	defer end(&err)
+	store, ok := As[BatchStore](s.Store)
+	if !ok {
+		for _, key := range keys {
+			if err := s.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	defer s.measure("mdelete", time.Now())
+	for _, key := range keys {
+		if err := HandlerBeforeDelete(*s, key); err != nil {
+			return err
+		}
+	}
+	err = store.MDelete(s.ctx, s.generateKeys(keys)...)
+	if err != nil {
+		return s.fail("", ReasonStore, err)
+	}
+	s.publish(InvalidateKeys, s.generateKeys(keys)...)
+
+	for _, key := range keys {
+		if err := HandlerAfterDelete(*s, key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -131,3 +535,11 @@ func (s *Schema[M]) generateKey(key string) string {
 	}
 	return key
 }
+
+func (s *Schema[M]) generateKeys(keys []string) []string {
+	storeKeys := make([]string, len(keys))
+	for i, key := range keys {
+		storeKeys[i] = s.generateKey(key)
+	}
+	return storeKeys
+}
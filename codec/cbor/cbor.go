@@ -0,0 +1,31 @@
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+const NAME = "cbor"
+
+func init() {
+	cacher.RegisterCodec(New())
+}
+
+// New returns a codec encoding values as CBOR.
+func New() cacher.Codec {
+	return codec{}
+}
+
+type codec struct{}
+
+func (codec) Name() string {
+	return NAME
+}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
@@ -0,0 +1,31 @@
+package msgpack
+
+import (
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const NAME = "msgpack"
+
+func init() {
+	cacher.RegisterCodec(New())
+}
+
+// New returns a codec encoding values as MessagePack.
+func New() cacher.Codec {
+	return codec{}
+}
+
+type codec struct{}
+
+func (codec) Name() string {
+	return NAME
+}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,34 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/codec/msgpack"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+type User struct {
+	Name  string
+	Email string
+	Age   int
+}
+
+func Test_Codec(t *testing.T) {
+	schema := cacher.NewSchema[User](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+		Codec: msgpack.New(),
+	})
+
+	err := schema.Set("1", User{Name: "John", Email: "john@gmail.com", Age: 30})
+	require.Nil(t, err)
+
+	user, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, User{Name: "John", Email: "john@gmail.com", Age: 30}, user)
+
+	codec, ok := cacher.LookupCodec(msgpack.NAME)
+	require.True(t, ok)
+	require.Equal(t, msgpack.NAME, codec.Name())
+}
@@ -0,0 +1,60 @@
+package protobuf
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/tinh-tinh/cacher/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+const NAME = "protobuf"
+
+var ErrNotMessage = errors.New("value is not a proto.Message")
+
+func init() {
+	cacher.RegisterCodec(New())
+}
+
+// New returns a codec encoding proto.Message values, such as the
+// *pb.User of a Schema[*pb.User], in the protobuf wire format.
+func New() cacher.Codec {
+	return codec{}
+}
+
+type codec struct{}
+
+func (codec) Name() string {
+	return NAME
+}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotMessage
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal fills a proto.Message, or allocates the message a pointer to
+// a message pointer refers to.
+func (codec) Unmarshal(data []byte, v any) error {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, msg)
+	}
+
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Pointer {
+		return ErrNotMessage
+	}
+	elem := reflect.New(ptr.Elem().Type().Elem())
+	msg, ok := elem.Interface().(proto.Message)
+	if !ok {
+		return ErrNotMessage
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	ptr.Elem().Set(elem)
+	return nil
+}
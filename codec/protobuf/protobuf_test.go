@@ -0,0 +1,31 @@
+package protobuf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/codec/protobuf"
+	"github.com/tinh-tinh/cacher/v2"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func Test_Codec(t *testing.T) {
+	schema := cacher.NewSchema[*wrapperspb.StringValue](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+		Codec: protobuf.New(),
+	})
+
+	err := schema.Set("1", wrapperspb.String("John"))
+	require.Nil(t, err)
+
+	val, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", val.GetValue())
+
+	other := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+		Codec: protobuf.New(),
+	})
+	require.ErrorIs(t, other.Set("1", "John"), protobuf.ErrNotMessage)
+}
@@ -0,0 +1,69 @@
+package cacher_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/tinh-tinh/tinhtinh/v2/common/compress"
+)
+
+func Test_Codec(t *testing.T) {
+	type User struct {
+		Name  string
+		Email string
+	}
+
+	for _, codec := range []cacher.Codec{cacher.JSON, cacher.Gob} {
+		schema := cacher.NewSchema[User](cacher.Config{
+			Store:       cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+			Codec:       codec,
+			CompressAlg: compress.Gzip,
+		})
+
+		err := schema.Set("1", User{Name: "John", Email: "john@gmail.com"})
+		require.Nil(t, err)
+
+		user, err := schema.Get("1")
+		require.Nil(t, err)
+		require.Equal(t, User{Name: "John", Email: "john@gmail.com"}, user)
+	}
+}
+
+func Test_RawCodec(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute})
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store: store,
+		Codec: cacher.Raw,
+	})
+
+	require.Nil(t, schema.Set("greeting", "hello"))
+	raw, err := store.Get(schema.GetCtx(), "greeting")
+	require.Nil(t, err)
+	require.True(t, bytes.HasSuffix(raw, []byte("hello")))
+
+	bytesSchema := cacher.NewSchema[[]byte](cacher.Config{
+		Store: store,
+		Codec: cacher.Raw,
+	})
+	val, err := bytesSchema.Get("greeting")
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), val)
+
+	// strings and bytes are stored as they are by default
+	plain := cacher.NewSchema[string](cacher.Config{Store: store})
+	require.Nil(t, plain.Set("name", "John"))
+	raw, err = store.Get(plain.GetCtx(), "name")
+	require.Nil(t, err)
+	require.True(t, bytes.HasSuffix(raw, []byte("John")))
+	require.False(t, bytes.Contains(raw, []byte(`"John"`)))
+
+	codec, ok := cacher.LookupCodec("raw")
+	require.True(t, ok)
+	require.Equal(t, cacher.Raw, codec)
+
+	_, ok = cacher.LookupCodec("yaml")
+	require.False(t, ok)
+}
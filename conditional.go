@@ -0,0 +1,121 @@
+package cacher
+
+import "errors"
+
+// UpdateRetries is how many times Update retries after a conflict.
+const UpdateRetries = 16
+
+// SetNX writes data only when key is missing and reports whether it did.
+func (s *Schema[M]) SetNX(key string, data M, opts ...StoreOptions) (_ bool, err error) {
+	s, end := s.trace("set_nx", key)
+	defer end(&err)
+
+	store, ok := As[ConditionalStore](s.Store)
+	if !ok {
+		return false, ErrNotSupported
+	}
+
+	if err := s.beforeSet(key, &data); err != nil {
+		return false, err
+	}
+	value, opts, err := s.prepare(key, data, 0, opts)
+	if err != nil {
+		return false, err
+	}
+	written, err := store.SetNX(s.ctx, s.generateKey(key), value, opts...)
+	if err != nil || !written {
+		return false, err
+	}
+	s.publish(InvalidateKeys, s.generateKey(key))
+	return true, HandlerAfterSet(*s, key, data)
+}
+
+// Replace writes data only when key exists and reports whether it did.
+func (s *Schema[M]) Replace(key string, data M, opts ...StoreOptions) (_ bool, err error) {
+	s, end := s.trace("replace", key)
+	defer end(&err)
+
+	store, ok := As[ConditionalStore](s.Store)
+	if !ok {
+		return false, ErrNotSupported
+	}
+
+	if err := s.beforeSet(key, &data); err != nil {
+		return false, err
+	}
+	value, opts, err := s.prepare(key, data, 0, opts)
+	if err != nil {
+		return false, err
+	}
+	written, err := store.Replace(s.ctx, s.generateKey(key), value, opts...)
+	if err != nil || !written {
+		return false, err
+	}
+	s.publish(InvalidateKeys, s.generateKey(key))
+	return true, HandlerAfterSet(*s, key, data)
+}
+
+// Update applies fnc to the current value of key and writes the result
+// unless the value changed in the meantime, in which case it reads the
+// value again and retries up to UpdateRetries times. A missing key is
+// passed to fnc as the zero value.
+func (s *Schema[M]) Update(key string, fnc func(old M) (M, error), opts ...StoreOptions) (_ M, err error) {
+	s, end := s.trace("update", key)
+	defer end(&err)
+
+	store, ok := As[ConditionalStore](s.Store)
+	if !ok {
+		return *new(M), ErrNotSupported
+	}
+
+	storeKey := s.generateKey(key)
+	for i := 0; i < UpdateRetries; i++ {
+		if err := HandlerBeforeGet(*s, key); err != nil {
+			return *new(M), err
+		}
+		raw, version, err := store.GetVersion(s.ctx, storeKey)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return *new(M), err
+		}
+
+		// an early expiry only asks loaders to refresh the value, it is
+		// still the value to update
+		old, _, err := s.readWith(key, raw, nil)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return *new(M), err
+		}
+
+		data, err := fnc(old)
+		if err != nil {
+			return *new(M), err
+		}
+
+		if err := s.beforeSet(key, &data); err != nil {
+			return *new(M), err
+		}
+		value, storeOpts, err := s.prepare(key, data, 0, opts)
+		if err != nil {
+			return *new(M), err
+		}
+
+		if version != nil {
+			err = store.CompareAndSwap(s.ctx, storeKey, value, version, storeOpts...)
+		} else {
+			var written bool
+			written, err = store.SetNX(s.ctx, storeKey, value, storeOpts...)
+			if err == nil && !written {
+				err = ErrConflict
+			}
+		}
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return *new(M), err
+		}
+		s.publish(InvalidateKeys, storeKey)
+
+		return data, HandlerAfterSet(*s, key, data)
+	}
+	return *new(M), ErrConflict
+}
@@ -0,0 +1,104 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Conditional(t *testing.T) {
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store:     cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+		Namespace: "users",
+	})
+
+	ok, err := schema.Replace("1", "John")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ok, err = schema.SetNX("1", "John")
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	ok, err = schema.SetNX("1", "Jane")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ok, err = schema.Replace("1", "Jane")
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	data, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", data)
+}
+
+func Test_Update(t *testing.T) {
+	schema := cacher.NewSchema[int](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := schema.Update("stock", func(old int) (int, error) {
+				return old + 1, nil
+			})
+			require.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	n, err := schema.Get("stock")
+	require.Nil(t, err)
+	require.Equal(t, 10, n)
+
+	errSoldOut := errors.New("sold out")
+	_, err = schema.Update("stock", func(old int) (int, error) {
+		return 0, errSoldOut
+	})
+	require.ErrorIs(t, err, errSoldOut)
+
+	n, err = schema.Get("stock")
+	require.Nil(t, err)
+	require.Equal(t, 10, n)
+
+	unsupported := cacher.NewSchema[int](cacher.Config{
+		Store: struct{ cacher.Store }{cacher.NewInMemory(cacher.StoreOptions{})},
+	})
+	_, err = unsupported.Update("stock", func(old int) (int, error) {
+		return old, nil
+	})
+	require.ErrorIs(t, err, cacher.ErrNotSupported)
+}
+
+func Test_UpdateRecompute(t *testing.T) {
+	schema := cacher.NewSchema[int](cacher.Config{
+		Store:     cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute}),
+		Ttl:       time.Minute,
+		Recompute: &cacher.XFetch{Beta: 1e6},
+	})
+
+	// the value always expires early for reads, but Update still sees it
+	n, err := schema.GetOrLoad("stock", func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 100, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 100, n)
+	_, err = schema.Get("stock")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	n, err = schema.Update("stock", func(old int) (int, error) {
+		return old + 1, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 101, n)
+}
@@ -5,4 +5,5 @@ const (
 	REDIS    = "redis_cache_manager"
 	MEMCACHE = "memcache_cache_manager"
 	SQLITE3  = "sqlite3_cache_manager"
+	TIERED   = "tiered_cache_manager"
 )
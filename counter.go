@@ -0,0 +1,57 @@
+package cacher
+
+import "errors"
+
+var ErrNotInteger = errors.New("value is not an integer")
+
+// Counter is a schema of int64 values that can be incremented atomically
+// on stores implementing CounterStore. Counters are stored as plain
+// decimals, so Get, Set and Delete work like any other schema. The codec,
+// compression and encryptor of config are ignored.
+type Counter struct {
+	*Schema[int64]
+}
+
+func NewCounter(config Config) *Counter {
+	config.Codec = JSON
+	config.CompressAlg = ""
+	config.Encryptor = nil
+	schema := NewSchema[int64](config)
+	schema.plain = true
+	return &Counter{Schema: schema}
+}
+
+func (c *Counter) Incr(key string, opts ...StoreOptions) (int64, error) {
+	return c.IncrBy(key, 1, opts...)
+}
+
+func (c *Counter) Decr(key string, opts ...StoreOptions) (int64, error) {
+	return c.IncrBy(key, -1, opts...)
+}
+
+// IncrBy adds delta to key and returns the new value. The ttl in opts, or
+// the schema Ttl, only applies when the key is created.
+func (c *Counter) IncrBy(key string, delta int64, opts ...StoreOptions) (_ int64, err error) {
+	s, end := c.trace("incr", key)
+	defer end(&err)
+
+	store, ok := As[CounterStore](s.Store)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+
+	var opt StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Ttl <= 0 {
+		opt.Ttl = s.Ttl
+	}
+
+	n, err := store.IncrBy(s.ctx, s.generateKey(key), delta, opt)
+	if err != nil {
+		return 0, err
+	}
+	s.publish(InvalidateKeys, s.generateKey(key))
+	return n, nil
+}
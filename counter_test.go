@@ -0,0 +1,77 @@
+package cacher_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Counter(t *testing.T) {
+	counter := cacher.NewCounter(cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "views",
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := counter.Incr("home", cacher.StoreOptions{Ttl: time.Minute})
+			require.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	n, err := counter.Get("home")
+	require.Nil(t, err)
+	require.Equal(t, int64(100), n)
+
+	ttl, err := counter.TTL("home")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, time.Minute)
+
+	n, err = counter.IncrBy("home", 10, cacher.StoreOptions{Ttl: time.Hour})
+	require.Nil(t, err)
+	require.Equal(t, int64(110), n)
+	ttl, err = counter.TTL("home")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, time.Minute)
+
+	n, err = counter.Decr("home")
+	require.Nil(t, err)
+	require.Equal(t, int64(109), n)
+
+	names := cacher.NewSchema[string](cacher.Config{
+		Store:     counter.Store,
+		Namespace: "views",
+	})
+	require.Nil(t, names.Set("name", "John"))
+	_, err = counter.Incr("name")
+	require.ErrorIs(t, err, cacher.ErrNotInteger)
+}
+
+func Test_CounterEncryptor(t *testing.T) {
+	counter := cacher.NewCounter(cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Encryptor: cacher.NewAESGCM(cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))),
+	})
+
+	_, err := counter.Incr("home")
+	require.Nil(t, err)
+	n, err := counter.Incr("home")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+
+	n, err = counter.Get("home")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+}
@@ -0,0 +1,211 @@
+package cacher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrUnknownKey is returned when a value was sealed with a key id
+	// missing from the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrSealed is returned when reading a sealed value without an Encryptor.
+	ErrSealed = errors.New("value is sealed and the schema has no encryptor")
+)
+
+// Encryptor seals values after they are encoded and compressed, and opens
+// them before decoding. aad holds the store key and the entry header,
+// binding a sealed value to the key and header it was written with. Open
+// returns ErrCorrupt for values that fail to authenticate, which schemas
+// evict, and ErrUnknownKey for values sealed with a key it does not have,
+// which they keep.
+type Encryptor interface {
+	Seal(plain []byte, aad []byte) ([]byte, error)
+	Open(sealed []byte, aad []byte) ([]byte, error)
+}
+
+// Keyring holds the keys of an Encryptor by id. New values are sealed with
+// the primary key, and any key in the ring opens the values it sealed, so
+// retired keys stay readable until they are removed.
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string][]byte
+}
+
+func NewKeyring(id string, key []byte) *Keyring {
+	return &Keyring{
+		primary: id,
+		keys:    map[string][]byte{id: key},
+	}
+}
+
+// Add adds a key used only to open values.
+func (k *Keyring) Add(id string, key []byte) {
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+}
+
+// Rotate adds a key and makes it primary, keeping the previous ones.
+func (k *Keyring) Rotate(id string, key []byte) {
+	k.mu.Lock()
+	k.keys[id] = key
+	k.primary = id
+	k.mu.Unlock()
+}
+
+// Remove drops a retired key, the values it sealed can no longer be read.
+func (k *Keyring) Remove(id string) {
+	k.mu.Lock()
+	if id != k.primary {
+		delete(k.keys, id)
+	}
+	k.mu.Unlock()
+}
+
+func (k *Keyring) current() (string, []byte) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary, k.keys[k.primary]
+}
+
+func (k *Keyring) key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// NewAESGCM returns an Encryptor encrypting and authenticating values with
+// AES-GCM. Keys must be 16, 24 or 32 bytes long.
+func NewAESGCM(keyring *Keyring) Encryptor {
+	return &aesGCM{keyring: keyring}
+}
+
+// NewHMAC returns an Encryptor that leaves values readable and only
+// appends an HMAC-SHA256 so tampered values are rejected.
+func NewHMAC(keyring *Keyring) Encryptor {
+	return &hmacSigner{keyring: keyring}
+}
+
+type aesGCM struct {
+	keyring *Keyring
+}
+
+func (a *aesGCM) Seal(plain []byte, aad []byte) ([]byte, error) {
+	id, key := a.keyring.current()
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := appendKeyID(make([]byte, 0, 1+len(id)+aead.NonceSize()+len(plain)+aead.Overhead()), id)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed = append(sealed, nonce...)
+	return aead.Seal(sealed, nonce, plain, aad), nil
+}
+
+func (a *aesGCM) Open(sealed []byte, aad []byte) ([]byte, error) {
+	id, rest, err := splitKeyID(sealed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := a.keyring.key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type hmacSigner struct {
+	keyring *Keyring
+}
+
+func (h *hmacSigner) Seal(plain []byte, aad []byte) ([]byte, error) {
+	id, key := h.keyring.current()
+	sealed, err := appendKeyID(make([]byte, 0, 1+len(id)+sha256.Size+len(plain)), id)
+	if err != nil {
+		return nil, err
+	}
+	sealed = append(sealed, sign(key, plain, aad)...)
+	return append(sealed, plain...), nil
+}
+
+func (h *hmacSigner) Open(sealed []byte, aad []byte) ([]byte, error) {
+	id, rest, err := splitKeyID(sealed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := h.keyring.key(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < sha256.Size {
+		return nil, ErrCorrupt
+	}
+	plain := rest[sha256.Size:]
+	if !hmac.Equal(rest[:sha256.Size], sign(key, plain, aad)) {
+		return nil, ErrCorrupt
+	}
+	return plain, nil
+}
+
+func sign(key []byte, plain []byte, aad []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(aad))))
+	mac.Write(aad)
+	mac.Write(plain)
+	return mac.Sum(nil)
+}
+
+// appendKeyID appends id prefixed with its length.
+func appendKeyID(buf []byte, id string) ([]byte, error) {
+	if len(id) > 255 {
+		return nil, fmt.Errorf("key id %q is too long", id)
+	}
+	buf = append(buf, byte(len(id)))
+	return append(buf, id...), nil
+}
+
+func splitKeyID(sealed []byte) (string, []byte, error) {
+	if len(sealed) == 0 || len(sealed) < 1+int(sealed[0]) {
+		return "", nil, ErrCorrupt
+	}
+	n := 1 + int(sealed[0])
+	return string(sealed[1:n]), sealed[n:], nil
+}
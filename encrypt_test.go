@@ -0,0 +1,126 @@
+package cacher_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Encryptor(t *testing.T) {
+	type Session struct {
+		Email string
+	}
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute})
+	ctx := context.Background()
+	keyring := cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))
+
+	schema := cacher.NewSchema[Session](cacher.Config{
+		Store:     store,
+		Namespace: "sessions",
+		Encryptor: cacher.NewAESGCM(keyring),
+	})
+	require.Nil(t, schema.Set("1", Session{Email: "john@gmail.com"}))
+
+	raw, err := store.Get(ctx, "sessions:1")
+	require.Nil(t, err)
+	require.False(t, bytes.Contains(raw, []byte("john@gmail.com")))
+
+	// values are bound to their key
+	require.Nil(t, store.Set(ctx, "sessions:2", raw))
+	_, err = schema.Get("2")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	// retired keys still open old values
+	keyring.Rotate("k2", bytes.Repeat([]byte{2}, 32))
+	session, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "john@gmail.com", session.Email)
+
+	// instances missing a key leave its values to the ones that have it
+	require.Nil(t, schema.Set("5", Session{Email: "jim@gmail.com"}))
+	stale := cacher.NewSchema[Session](cacher.Config{
+		Store:     store,
+		Namespace: "sessions",
+		Encryptor: cacher.NewAESGCM(cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))),
+	})
+	_, err = stale.Get("5")
+	require.ErrorIs(t, err, cacher.ErrUnknownKey)
+	session, err = schema.Get("5")
+	require.Nil(t, err)
+	require.Equal(t, "jim@gmail.com", session.Email)
+
+	keyring.Remove("k1")
+	_, err = schema.Get("1")
+	require.ErrorIs(t, err, cacher.ErrUnknownKey)
+
+	// values that were not sealed are rejected
+	require.Nil(t, store.Set(ctx, "sessions:3", []byte(`{"Email":"jane@gmail.com"}`)))
+	_, err = schema.Get("3")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	plain := cacher.NewSchema[Session](cacher.Config{Store: store, Namespace: "sessions"})
+	require.Nil(t, schema.Set("4", Session{Email: "jane@gmail.com"}))
+	_, err = plain.Get("4")
+	require.ErrorIs(t, err, cacher.ErrSealed)
+}
+
+func Test_HMAC(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute})
+	ctx := context.Background()
+
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Encryptor: cacher.NewHMAC(cacher.NewKeyring("k1", []byte("secret"))),
+	})
+	require.Nil(t, schema.Set("role", "user"))
+
+	raw, err := store.Get(ctx, "role")
+	require.Nil(t, err)
+	require.True(t, bytes.HasSuffix(raw, []byte("user")))
+
+	val, err := schema.Get("role")
+	require.Nil(t, err)
+	require.Equal(t, "user", val)
+
+	forged := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Encryptor: cacher.NewHMAC(cacher.NewKeyring("k1", []byte("guess"))),
+	})
+	require.Nil(t, forged.Set("role", "root"))
+	_, err = schema.Get("role")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+func Test_EncryptorHeader(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{})
+	ctx := context.Background()
+
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Ttl:       time.Minute,
+		StaleTtl:  time.Second,
+		Encryptor: cacher.NewAESGCM(cacher.NewKeyring("k1", bytes.Repeat([]byte{1}, 32))),
+	})
+	require.Nil(t, schema.Set("token", "secret"))
+
+	// push the deadline back and fix up the checksum
+	raw, err := store.Get(ctx, "token")
+	require.Nil(t, err)
+	forged := append([]byte{}, raw...)
+	n := 7 + int(forged[6])
+	binary.BigEndian.PutUint64(forged[n+16:], uint64(time.Now().Add(time.Hour).UnixNano()))
+	crc := crc32.NewIEEE()
+	crc.Write(forged[:n+32])
+	crc.Write(forged[n+36:])
+	binary.BigEndian.PutUint32(forged[n+32:], crc.Sum32())
+	require.Nil(t, store.Set(ctx, "token", forged))
+
+	_, err = schema.Get("token")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
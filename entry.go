@@ -0,0 +1,137 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+
+	"github.com/tinh-tinh/tinhtinh/v2/common/compress"
+)
+
+// ErrCorrupt is returned for stored values whose header does not match
+// their content. Schemas evict them and report a miss.
+var ErrCorrupt = errors.New("corrupt cache entry")
+
+const entryVersion byte = 1
+
+// entrySealed flags values sealed by an Encryptor.
+const entrySealed byte = 1
+
+var entryMagic = []byte{0x00, 't', 'c'}
+
+// algIDs numbers the compression algorithms recorded in entry headers.
+var algIDs = map[compress.Alg]byte{
+	"":             0,
+	compress.Gzip:  1,
+	compress.Flate: 2,
+	compress.Zlib:  3,
+}
+
+// entry wraps a value written by a Schema with the header describing how
+// to decode it and the expiry information the schema enforces itself,
+// independent of the store's own TTL.
+//
+// The header is laid out as magic, version, compression alg, flags, codec
+// name length, codec name, then created-at, expiry, deadline and delta as
+// big endian int64s, and a CRC32 of everything else.
+type entry struct {
+	// legacy is set for values written without a header.
+	legacy bool
+	codec  string
+	alg    compress.Alg
+	sealed bool
+	// created is when the value was written, in unix nanoseconds.
+	created int64
+	// expiry is when the value turns stale, in unix nanoseconds.
+	expiry int64
+	// deadline is when the value can no longer be served, in unix nanoseconds.
+	deadline int64
+	// delta is how long the value took to compute, in nanoseconds.
+	delta int64
+	value []byte
+}
+
+func (e entry) encode() []byte {
+	buf := e.header()
+	buf = binary.BigEndian.AppendUint32(buf, checksum(buf, e.value))
+	return append(buf, e.value...)
+}
+
+// header encodes the header of e up to its checksum.
+func (e entry) header() []byte {
+	size := 4 + 3 + len(e.codec) + 4*8
+	buf := make([]byte, size, size+4+len(e.value))
+	copy(buf, entryMagic)
+	buf[3] = entryVersion
+	buf[4] = algIDs[e.alg]
+	if e.sealed {
+		buf[5] |= entrySealed
+	}
+	buf[6] = byte(len(e.codec))
+	n := 7 + copy(buf[7:], e.codec)
+	binary.BigEndian.PutUint64(buf[n:], uint64(e.created))
+	binary.BigEndian.PutUint64(buf[n+8:], uint64(e.expiry))
+	binary.BigEndian.PutUint64(buf[n+16:], uint64(e.deadline))
+	binary.BigEndian.PutUint64(buf[n+24:], uint64(e.delta))
+	return buf
+}
+
+// decodeEntry reads the header of raw. Values without the magic prefix
+// are returned as legacy entries holding raw.
+func decodeEntry(raw []byte) (entry, error) {
+	if !bytes.HasPrefix(raw, entryMagic) || len(raw) < 4 {
+		return entry{legacy: true, value: raw}, nil
+	}
+
+	if raw[3] != entryVersion || len(raw) < 7 {
+		return entry{}, ErrCorrupt
+	}
+	n := 7 + int(raw[6])
+	if len(raw) < n+36 {
+		return entry{}, ErrCorrupt
+	}
+	alg, ok := algByID(raw[4])
+	if !ok {
+		return entry{}, ErrCorrupt
+	}
+	value := raw[n+36:]
+	if binary.BigEndian.Uint32(raw[n+32:]) != checksum(raw[:n+32], value) {
+		return entry{}, ErrCorrupt
+	}
+	return entry{
+		codec:    string(raw[7:n]),
+		alg:      alg,
+		sealed:   raw[5]&entrySealed != 0,
+		created:  int64(binary.BigEndian.Uint64(raw[n:])),
+		expiry:   int64(binary.BigEndian.Uint64(raw[n+8:])),
+		deadline: int64(binary.BigEndian.Uint64(raw[n+16:])),
+		delta:    int64(binary.BigEndian.Uint64(raw[n+24:])),
+		value:    value,
+	}, nil
+}
+
+func algByID(id byte) (compress.Alg, bool) {
+	for alg, algID := range algIDs {
+		if algID == id {
+			return alg, true
+		}
+	}
+	return "", false
+}
+
+func checksum(header []byte, value []byte) uint32 {
+	crc := crc32.NewIEEE()
+	crc.Write(header)
+	crc.Write(value)
+	return crc.Sum32()
+}
+
+func (e entry) stale(now time.Time) bool {
+	return e.expiry != 0 && now.UnixNano() >= e.expiry
+}
+
+func (e entry) dead(now time.Time) bool {
+	return e.deadline != 0 && now.UnixNano() >= e.deadline
+}
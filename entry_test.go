@@ -0,0 +1,49 @@
+package cacher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/tinh-tinh/tinhtinh/v2/common/compress"
+)
+
+func Test_Entry(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute})
+	ctx := context.Background()
+
+	gzipped := cacher.NewSchema[User](cacher.Config{
+		Store:       store,
+		CompressAlg: compress.Gzip,
+	})
+	require.Nil(t, gzipped.Set("1", User{Name: "John"}))
+
+	// the header, not the config, decides how values are decoded
+	plain := cacher.NewSchema[User](cacher.Config{Store: store})
+	user, err := plain.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", user.Name)
+
+	// legacy values without a header are still read
+	require.Nil(t, store.Set(ctx, "2", []byte(`{"Name":"Jane"}`)))
+	user, err = plain.Get("2")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", user.Name)
+
+	// corrupt values are evicted
+	raw, err := store.Get(ctx, "1")
+	require.Nil(t, err)
+	corrupt := append([]byte{}, raw...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	require.Nil(t, store.Set(ctx, "1", corrupt))
+
+	_, err = plain.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	_, err = store.Get(ctx, "1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
@@ -0,0 +1,218 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tinh-tinh/tinhtinh/v2/core"
+)
+
+// EvictOptions lists what Evict removes once a request succeeds. Patterns
+// take route params as {id}, query params as {query.page} and JSON body
+// fields as {body.user.id}. A pattern naming a value the request does not
+// have is skipped.
+type EvictOptions struct {
+	// Keys are deleted from the config namespace.
+	Keys []string
+	// Prefixes delete every key starting with them in the config
+	// namespace, on stores implementing PrefixStore.
+	Prefixes []string
+	// Tags are invalidated on stores implementing TagStore. PathTag names
+	// the responses ResponseCache cached for a path.
+	Tags []string
+	// Methods evicting, POST, PUT, PATCH and DELETE when empty.
+	Methods []string
+	// MaxBody caps the bytes of body read for {body.} patterns,
+	// DefaultMaxBody when 0. Larger bodies still reach the handler whole,
+	// but their body patterns are skipped and ErrBodyTooLarge is reported.
+	MaxBody int64
+}
+
+// DefaultMaxBody is the EvictOptions.MaxBody used when it is 0.
+const DefaultMaxBody = 1 << 20
+
+// ErrBodyTooLarge is reported to the Error hooks when Evict skips the
+// body patterns of a request over EvictOptions.MaxBody.
+var ErrBodyTooLarge = errors.New("request body too large to evict from")
+
+// Evict returns a middleware removing the keys, prefixes and tags of opt
+// from the config store after a 2xx response. Keys and prefixes go through
+// Schema.Delete, so delete hooks and the Bus see them. Failures are
+// reported to the Error hooks, the response having already been sent.
+func (c *Config) Evict(opt EvictOptions) core.Middleware {
+	if len(opt.Methods) == 0 {
+		opt.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if opt.MaxBody <= 0 {
+		opt.MaxBody = DefaultMaxBody
+	}
+	patterns := slices.Concat(opt.Keys, opt.Prefixes, opt.Tags)
+	readBody := slices.ContainsFunc(patterns, func(pattern string) bool {
+		return strings.Contains(pattern, "{body.")
+	})
+	// the schema only deletes, so it does not subscribe to store events
+	schema := newSchema[any](*c)
+
+	return func(ctx core.Ctx) error {
+		r := ctx.Req()
+		if !slices.Contains(opt.Methods, r.Method) {
+			return ctx.Next()
+		}
+
+		var body any
+		var bodyErr error
+		if readBody && r.Body != nil {
+			raw, err := io.ReadAll(io.LimitReader(r.Body, opt.MaxBody+1))
+			if err != nil {
+				return err
+			}
+			if int64(len(raw)) > opt.MaxBody {
+				r.Body = replayBody{io.MultiReader(bytes.NewReader(raw), r.Body), r.Body}
+				bodyErr = ErrBodyTooLarge
+			} else {
+				r.Body = io.NopCloser(bytes.NewReader(raw))
+				json.Unmarshal(raw, &body)
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: ctx.Res()}
+		ctx.SetCtx(rec, r)
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+		if rec.status != 0 && (rec.status < 200 || rec.status >= 300) {
+			return nil
+		}
+
+		expand := func(patterns []string) []string {
+			values := make([]string, 0, len(patterns))
+			for _, pattern := range patterns {
+				if value, ok := expandPattern(pattern, ctx, body); ok {
+					values = append(values, value)
+				}
+			}
+			return values
+		}
+		s := schema.WithCtx(r.Context())
+		if bodyErr != nil {
+			s.fail("", ReasonCodec, bodyErr)
+		}
+		if keys := expand(opt.Keys); len(keys) > 0 {
+			s.MDelete(keys...)
+		}
+		for _, prefix := range expand(opt.Prefixes) {
+			s.deletePrefix(prefix)
+		}
+		if tags := expand(opt.Tags); len(tags) > 0 {
+			if err := s.InvalidateTags(tags...); err != nil {
+				s.fail("", ReasonStore, err)
+			}
+		}
+		return nil
+	}
+}
+
+// replayBody reads the bytes read ahead of the rest of a request body.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// deletePrefix deletes every key starting with prefix in the namespace.
+func (s *Schema[M]) deletePrefix(prefix string) error {
+	store, ok := As[PrefixStore](s.Store)
+	if !ok {
+		return s.fail(prefix, ReasonStore, ErrNotSupported)
+	}
+	keys, err := store.Keys(s.ctx, s.generateKey(prefix))
+	if err != nil {
+		return s.fail(prefix, ReasonStore, err)
+	}
+	for i := range keys {
+		keys[i] = strings.TrimPrefix(keys[i], s.prefix())
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.MDelete(keys...)
+}
+
+// expandPattern replaces the {param}, {query.name} and {body.field}
+// placeholders of pattern with the values of the request.
+func expandPattern(pattern string, ctx core.Ctx, body any) (string, bool) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			b.WriteString(pattern)
+			return b.String(), true
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end < 0 {
+			b.WriteString(pattern)
+			return b.String(), true
+		}
+		name := pattern[start+1 : start+end]
+
+		var value string
+		switch {
+		case strings.HasPrefix(name, "body."):
+			field, ok := lookupField(body, strings.Split(strings.TrimPrefix(name, "body."), "."))
+			if !ok {
+				return "", false
+			}
+			value = fmt.Sprint(field)
+		case strings.HasPrefix(name, "query."):
+			value = ctx.Query(strings.TrimPrefix(name, "query."))
+		default:
+			value = ctx.Path(name)
+		}
+		if value == "" {
+			return "", false
+		}
+
+		b.WriteString(pattern[:start])
+		b.WriteString(value)
+		pattern = pattern[start+end+1:]
+	}
+}
+
+func lookupField(v any, path []string) (any, bool) {
+	for _, name := range path {
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		v, ok = fields[name]
+		if !ok || v == nil {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// statusRecorder keeps the status of the response passing through.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
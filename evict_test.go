@@ -0,0 +1,189 @@
+package cacher_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/tinh-tinh/tinhtinh/v2/core"
+)
+
+func Test_Evict(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	ctx := context.Background()
+
+	var deleted []string
+	calls := 0
+	userController := func(module core.Module) core.Controller {
+		cache := cacher.Inject(module)
+		ctrl := module.NewController("users")
+		ctrl.Use(cache.ResponseCache()).Get("{id}", func(ctx core.Ctx) error {
+			calls++
+			return ctx.JSON(core.Map{"data": calls})
+		})
+
+		evict := cache.Evict(cacher.EvictOptions{
+			Keys:     []string{"user:{id}", "user:{body.missing}"},
+			Prefixes: []string{"team:{body.team.id}:"},
+			Tags:     []string{cacher.PathTag("/api/users/{id}")},
+		})
+		ctrl.Use(evict).Put("{id}", func(ctx core.Ctx) error {
+			if ctx.Query("fail") != "" {
+				return ctx.Status(http.StatusBadRequest).JSON(core.Map{"error": "invalid"})
+			}
+			body, err := io.ReadAll(ctx.Req().Body)
+			if err != nil {
+				return err
+			}
+			return ctx.JSON(core.Map{"data": string(body)})
+		})
+		return ctrl
+	}
+
+	appModule := func() core.Module {
+		return core.NewModule(core.NewModuleOptions{
+			Imports: []core.Modules{
+				cacher.Register(cacher.Config{
+					Store: memory,
+					Hooks: []cacher.Hook{
+						{Key: cacher.AfterDelete, Fnc: func(key string, data any) {
+							deleted = append(deleted, key)
+						}},
+					},
+				}),
+			},
+			Controllers: []core.Controllers{userController},
+		})
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	get := func() string {
+		resp, err := testServer.Client().Get(testServer.URL + "/api/users/1")
+		require.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return string(body)
+	}
+	put := func(query string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, testServer.URL+"/api/users/1"+query, strings.NewReader(`{"team":{"id":7}}`))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := testServer.Client().Do(req)
+		require.Nil(t, err)
+		return resp
+	}
+	seed := func() {
+		for _, key := range []string{"user:1", "team:7:a", "team:7:b", "team:8:a"} {
+			require.Nil(t, memory.Set(ctx, key, []byte(`"x"`)))
+		}
+	}
+
+	seed()
+	require.Equal(t, `{"data":1}`, get())
+	require.Equal(t, `{"data":1}`, get())
+
+	resp := put("?fail=1")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, `{"data":1}`, get())
+	require.Empty(t, deleted)
+
+	resp = put("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, `{"data":"{\"team\":{\"id\":7}}"}`, string(body))
+
+	require.Equal(t, `{"data":2}`, get())
+	require.ElementsMatch(t, []string{"user:1", "team:7:a", "team:7:b"}, deleted)
+	_, err = memory.Get(ctx, "team:8:a")
+	require.Nil(t, err)
+}
+
+func Test_EvictMaxBody(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	ctx := context.Background()
+
+	var errs []error
+	userController := func(module core.Module) core.Controller {
+		cache := cacher.Inject(module)
+		ctrl := module.NewController("users")
+
+		evict := cache.Evict(cacher.EvictOptions{
+			Keys:    []string{"user:{id}", "team:{body.team}"},
+			MaxBody: 16,
+		})
+		ctrl.Use(evict).Put("{id}", func(ctx core.Ctx) error {
+			body, err := io.ReadAll(ctx.Req().Body)
+			if err != nil {
+				return err
+			}
+			return ctx.JSON(core.Map{"data": len(body)})
+		})
+		return ctrl
+	}
+
+	appModule := func() core.Module {
+		return core.NewModule(core.NewModuleOptions{
+			Imports: []core.Modules{
+				cacher.Register(cacher.Config{
+					Store: memory,
+					Hooks: []cacher.Hook{
+						{Key: cacher.Error, Fnc: func(key string, data any) {
+							errs = append(errs, data.(cacher.Event).Err)
+						}},
+					},
+				}),
+			},
+			Controllers: []core.Controllers{userController},
+		})
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	put := func(body string) string {
+		req, err := http.NewRequest(http.MethodPut, testServer.URL+"/api/users/1", strings.NewReader(body))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := testServer.Client().Do(req)
+		require.Nil(t, err)
+		data, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return string(data)
+	}
+	seed := func() {
+		for _, key := range []string{"user:1", "team:7"} {
+			require.Nil(t, memory.Set(ctx, key, []byte(`"x"`)))
+		}
+	}
+
+	seed()
+	require.Equal(t, `{"data":10}`, put(`{"team":7}`))
+	_, err := memory.Get(ctx, "team:7")
+	require.NotNil(t, err)
+	require.Empty(t, errs)
+
+	seed()
+	large := `{"team":7,"name":"` + strings.Repeat("a", 64) + `"}`
+	require.Equal(t, fmt.Sprintf(`{"data":%d}`, len(large)), put(large))
+	_, err = memory.Get(ctx, "user:1")
+	require.NotNil(t, err)
+	_, err = memory.Get(ctx, "team:7")
+	require.Nil(t, err)
+	require.Equal(t, []error{cacher.ErrBodyTooLarge}, errs)
+}
@@ -1,6 +1,12 @@
 package cacher
 
-import "slices"
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 type HookKey string
 
@@ -11,71 +17,287 @@ const (
 	AfterSet     HookKey = "after_set"
 	BeforeDelete HookKey = "before_delete"
 	AfterDelete  HookKey = "after_delete"
+	// Hit and Miss follow every read of a key.
+	Hit  HookKey = "hit"
+	Miss HookKey = "miss"
+	// Evict follows a value removed to make room or because it is corrupt.
+	Evict HookKey = "evict"
+	// Expire follows a value removed by a store once its ttl ran out.
+	Expire HookKey = "expire"
+	// Error follows a failed store, codec or bus operation, or a failed
+	// background refresh.
+	Error HookKey = "error"
+	// Clear follows Schema.Clear, with the cleared prefix as Event.Key.
+	Clear HookKey = "clear"
+)
+
+// Reasons carried by events.
+const (
+	ReasonNotFound  = "not_found"
+	ReasonExpired   = "expired"
+	ReasonRecompute = "recompute"
+	ReasonCorrupt   = "corrupt"
+	ReasonCapacity  = "capacity"
+	ReasonTtl       = "ttl"
+	ReasonStore     = "store"
+	ReasonCodec     = "codec"
+	ReasonBus       = "bus"
+	ReasonLoad      = "load"
+	ReasonClear     = "clear"
 )
 
+// Event is the data hooks receive for Hit, Miss, Evict, Expire, Error and
+// Clear.
+type Event struct {
+	// Key is the key in the store, including the namespace.
+	Key    string
+	Reason string
+	Err    error
+}
+
 type HookFnc func(key string, data interface{})
 
+// HookHandler receives the schema context. An error from a Before* hook
+// cancels the operation, an error from an After* hook is returned once the
+// operation is done.
+type HookHandler func(ctx context.Context, key string, data interface{}) error
+
+// Hook runs Handler, or Fnc, on Key. Every hook registered for a key runs,
+// highest Priority first and in registration order among equal ones. Async
+// hooks run in their own goroutine and cannot cancel the operation. A
+// panicking hook is recovered and reported as an error.
 type Hook struct {
-	Key HookKey
-	Fnc HookFnc
+	Key      HookKey
+	Fnc      HookFnc
+	Handler  HookHandler
+	Priority int
+	Async    bool
 }
 
-func HandlerBeforeGet[M any](schema Schema[M], key string) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == BeforeGet
+// runHooks runs the hooks registered for hookKey in order and returns the
+// first error, skipping the hooks after it.
+func runHooks(ctx context.Context, hooks []Hook, hookKey HookKey, key string, data interface{}) error {
+	matched := make([]Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook.Key == hookKey {
+			matched = append(matched, hook)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b Hook) int {
+		return cmp.Compare(b.Priority, a.Priority)
 	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, nil)
+
+	for _, hook := range matched {
+		if hook.Async {
+			go hook.call(context.WithoutCancel(ctx), key, data)
+			continue
+		}
+		if err := hook.call(ctx, key, data); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func HandlerAfterGet[M any](schema Schema[M], key string, data M) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == AfterGet
-	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, data)
+func (h Hook) call(ctx context.Context, key string, data interface{}) (err error) {
+	defer recoverHook(h.Key, &err)
+
+	if h.Handler != nil {
+		return h.Handler(ctx, key, data)
+	}
+	if h.Fnc != nil {
+		h.Fnc(key, data)
 	}
+	return nil
 }
 
-func HandlerBeforeSet[M any](schema Schema[M], key string, data M) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == BeforeSet
-	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, data)
+// recoverHook turns a panic in a hook into an error.
+func recoverHook(hook HookKey, err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("cacher: %s hook panicked: %v", hook, r)
 	}
 }
 
-func HandlerAfterSet[M any](schema Schema[M], key string, data M) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == AfterSet
-	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, data)
+// typedHooks are the hooks registered on a schema with its value type.
+// They run after the untyped Config.Hooks, in registration order.
+type typedHooks[M any] struct {
+	beforeGet    []func(ctx context.Context, key string) error
+	afterGet     []func(ctx context.Context, key string, v M)
+	beforeSet    []func(ctx context.Context, key string, v *M) error
+	afterSet     []func(ctx context.Context, key string, v M)
+	beforeDelete []func(ctx context.Context, key string) error
+	afterDelete  []func(ctx context.Context, key string)
+}
+
+// OnBeforeGet runs fnc before every read, an error cancels the read.
+func (s *Schema[M]) OnBeforeGet(fnc func(ctx context.Context, key string) error) {
+	s.typed.beforeGet = append(s.typed.beforeGet, fnc)
+}
+
+// OnAfterGet runs fnc with every value read.
+func (s *Schema[M]) OnAfterGet(fnc func(ctx context.Context, key string, v M)) {
+	s.typed.afterGet = append(s.typed.afterGet, fnc)
+}
+
+// OnBeforeSet runs fnc before every write. It may change the value before
+// it is encoded, an error cancels the write.
+func (s *Schema[M]) OnBeforeSet(fnc func(ctx context.Context, key string, v *M) error) {
+	s.typed.beforeSet = append(s.typed.beforeSet, fnc)
+}
+
+// OnAfterSet runs fnc with every value written.
+func (s *Schema[M]) OnAfterSet(fnc func(ctx context.Context, key string, v M)) {
+	s.typed.afterSet = append(s.typed.afterSet, fnc)
+}
+
+// OnBeforeDelete runs fnc before every delete, an error cancels it.
+func (s *Schema[M]) OnBeforeDelete(fnc func(ctx context.Context, key string) error) {
+	s.typed.beforeDelete = append(s.typed.beforeDelete, fnc)
+}
+
+// OnAfterDelete runs fnc after every delete.
+func (s *Schema[M]) OnAfterDelete(fnc func(ctx context.Context, key string)) {
+	s.typed.afterDelete = append(s.typed.afterDelete, fnc)
+}
+
+func HandlerBeforeGet[M any](schema Schema[M], key string) (err error) {
+	if err := runHooks(schema.ctx, schema.GetHooks(), BeforeGet, key, nil); err != nil {
+		return err
 	}
+	defer recoverHook(BeforeGet, &err)
+	for _, fnc := range schema.typed.beforeGet {
+		if err := fnc(schema.ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func HandlerBeforeDelete[M any](schema Schema[M], key string) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == BeforeDelete
-	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, nil)
+func HandlerAfterGet[M any](schema Schema[M], key string, data M) (err error) {
+	if err := runHooks(schema.ctx, schema.GetHooks(), AfterGet, key, data); err != nil {
+		return err
+	}
+	defer recoverHook(AfterGet, &err)
+	for _, fnc := range schema.typed.afterGet {
+		fnc(schema.ctx, key, data)
 	}
+	return nil
 }
 
-func HandlerAfterDelete[M any](schema Schema[M], key string) {
-	hooks := schema.GetHooks()
-	findHook := slices.IndexFunc(hooks, func(h Hook) bool {
-		return h.Key == AfterDelete
+// HandlerBeforeSet runs the before-set hooks on a copy of data, use
+// Schema.beforeSet to keep the changes typed hooks make.
+func HandlerBeforeSet[M any](schema Schema[M], key string, data M) error {
+	return schema.beforeSet(key, &data)
+}
+
+func HandlerAfterSet[M any](schema Schema[M], key string, data M) (err error) {
+	if err := runHooks(schema.ctx, schema.GetHooks(), AfterSet, key, data); err != nil {
+		return err
+	}
+	defer recoverHook(AfterSet, &err)
+	for _, fnc := range schema.typed.afterSet {
+		fnc(schema.ctx, key, data)
+	}
+	return nil
+}
+
+func HandlerBeforeDelete[M any](schema Schema[M], key string) (err error) {
+	if err := runHooks(schema.ctx, schema.GetHooks(), BeforeDelete, key, nil); err != nil {
+		return err
+	}
+	defer recoverHook(BeforeDelete, &err)
+	for _, fnc := range schema.typed.beforeDelete {
+		if err := fnc(schema.ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func HandlerAfterDelete[M any](schema Schema[M], key string) (err error) {
+	if err := runHooks(schema.ctx, schema.GetHooks(), AfterDelete, key, nil); err != nil {
+		return err
+	}
+	defer recoverHook(AfterDelete, &err)
+	for _, fnc := range schema.typed.afterDelete {
+		fnc(schema.ctx, key)
+	}
+	return nil
+}
+
+// beforeSet runs the before-set hooks, letting typed ones change data.
+func (s *Schema[M]) beforeSet(key string, data *M) (err error) {
+	if err := runHooks(s.ctx, s.Hooks, BeforeSet, key, *data); err != nil {
+		return err
+	}
+	defer recoverHook(BeforeSet, &err)
+	for _, fnc := range s.typed.beforeSet {
+		if err := fnc(s.ctx, key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit runs the hooks of an event on key, their errors are dropped since
+// the operation has already settled.
+func (s *Schema[M]) emit(hook HookKey, key string, reason string, err error) {
+	if s.Metrics != nil {
+		s.Metrics.event(s.Store.Name(), s.Namespace, hook, reason)
+	}
+	if s.traced != nil {
+		switch hook {
+		case Hit:
+			s.traced.hits++
+		case Miss:
+			s.traced.misses++
+		}
+	}
+	runHooks(s.ctx, s.Hooks, hook, key, Event{
+		Key:    s.generateKey(key),
+		Reason: reason,
+		Err:    err,
 	})
-	if findHook != -1 {
-		hooks[findHook].Fnc(key, nil)
+}
+
+// fail raises an Error event for err and returns it.
+func (s *Schema[M]) fail(key string, reason string, err error) error {
+	s.emit(Error, key, reason, err)
+	return err
+}
+
+// watch forwards the evictions and expiries the store reports in the
+// config namespace to the Evict and Expire hooks and to the metrics. It
+// returns the function stopping it, nil when nothing listens.
+func (c Config) watch() func() {
+	store, ok := As[EventStore](c.Store)
+	if !ok || c.Metrics == nil && !slices.ContainsFunc(c.Hooks, func(h Hook) bool {
+		return h.Key == Evict || h.Key == Expire
+	}) {
+		return nil
+	}
+
+	prefix := ""
+	if c.Namespace != "" {
+		prefix = c.Namespace + ":"
+	}
+	return store.Subscribe(func(hook HookKey, event Event) {
+		if !strings.HasPrefix(event.Key, prefix) {
+			return
+		}
+		if c.Metrics != nil {
+			c.Metrics.event(c.Store.Name(), c.Namespace, hook, event.Reason)
+		}
+		runHooks(context.Background(), c.Hooks, hook, strings.TrimPrefix(event.Key, prefix), event)
+	})
+}
+
+// Close stops forwarding store events to the schema hooks. Schemas made
+// with NewSchema on a store implementing EventStore stay subscribed to it
+// until closed, schemas from InjectSchema share the subscription of their
+// config and need no Close.
+func (s *Schema[M]) Close() {
+	if s.unwatch != nil {
+		s.unwatch()
 	}
 }
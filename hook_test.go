@@ -1,7 +1,10 @@
 package cacher_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -57,3 +60,217 @@ func Test_Hook(t *testing.T) {
 	require.NotNil(t, err)
 	require.Empty(t, data)
 }
+
+func Test_Hooks(t *testing.T) {
+	type ctxKey struct{}
+	errReadOnly := errors.New("read only")
+	calls := []string{}
+	async := make(chan string, 1)
+
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Hooks: []cacher.Hook{
+			{Key: cacher.AfterSet, Fnc: func(key string, val any) {
+				calls = append(calls, "first")
+			}},
+			{Key: cacher.AfterSet, Fnc: func(key string, val any) {
+				calls = append(calls, "second")
+			}},
+			{Key: cacher.AfterSet, Priority: 10, Handler: func(ctx context.Context, key string, val any) error {
+				calls = append(calls, ctx.Value(ctxKey{}).(string))
+				return nil
+			}},
+			{Key: cacher.AfterSet, Async: true, Handler: func(ctx context.Context, key string, val any) error {
+				async <- key
+				return nil
+			}},
+			{Key: cacher.BeforeSet, Handler: func(ctx context.Context, key string, val any) error {
+				if key == "admin" {
+					return errReadOnly
+				}
+				return nil
+			}},
+			{Key: cacher.BeforeDelete, Fnc: func(key string, val any) {
+				panic("boom")
+			}},
+		},
+	})
+	cache.SetCtx(context.WithValue(context.Background(), ctxKey{}, "ctx"))
+
+	require.Nil(t, cache.Set("1", "John"))
+	require.Equal(t, []string{"ctx", "first", "second"}, calls)
+	require.Equal(t, "1", <-async)
+
+	// a Before* error vetoes the operation
+	require.ErrorIs(t, cache.Set("admin", "John"), errReadOnly)
+	_, err := cache.Get("admin")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	// a panic is reported as an error
+	err = cache.Delete("1")
+	require.ErrorContains(t, err, "panicked")
+	data, err := cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+}
+
+func Test_HookPriorityBounds(t *testing.T) {
+	var calls []string
+	hook := func(name string, priority int) cacher.Hook {
+		return cacher.Hook{Key: cacher.AfterSet, Priority: priority, Fnc: func(key string, val any) {
+			calls = append(calls, name)
+		}}
+	}
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute}),
+		Hooks: []cacher.Hook{
+			hook("min", math.MinInt),
+			hook("zero", 0),
+			hook("max", math.MaxInt),
+		},
+	})
+
+	require.Nil(t, cache.Set("1", "John"))
+	require.Equal(t, []string{"max", "zero", "min"}, calls)
+}
+
+func Test_Events(t *testing.T) {
+	events := make(chan string, 16)
+	record := func(key string, data any) {
+		event := data.(cacher.Event)
+		events <- fmt.Sprintf("%s %s %v", event.Key, event.Reason, event.Err)
+	}
+
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl:      15 * time.Minute,
+			MaxItems: 1,
+		}),
+		Namespace: "events",
+		Hooks: []cacher.Hook{
+			{Key: cacher.Hit, Fnc: record},
+			{Key: cacher.Miss, Fnc: record},
+			{Key: cacher.Evict, Fnc: record},
+			{Key: cacher.Clear, Fnc: record},
+		},
+	})
+	defer cache.Close()
+
+	_, err := cache.Get("a")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	require.Equal(t, "events:a not_found <nil>", <-events)
+
+	require.Nil(t, cache.Set("a", "1"))
+	_, err = cache.Get("a")
+	require.Nil(t, err)
+	require.Equal(t, "events:a  <nil>", <-events)
+
+	require.Nil(t, cache.Set("b", "2"))
+	require.Equal(t, "events:a capacity <nil>", <-events)
+
+	require.Nil(t, cache.Clear())
+	require.Equal(t, "events: clear <nil>", <-events)
+
+	failed := errors.New("down")
+	broken := cacher.NewSchema[string](cacher.Config{
+		Store: &failingStore{Store: cacher.NewInMemory(cacher.StoreOptions{}), err: failed},
+		Hooks: []cacher.Hook{
+			{Key: cacher.Error, Fnc: record},
+		},
+	})
+	require.ErrorIs(t, broken.Set("a", "1"), failed)
+	require.Equal(t, "a store down", <-events)
+}
+
+func Test_ExpireEvent(t *testing.T) {
+	expired := make(chan cacher.Event, 1)
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{}),
+		Hooks: []cacher.Hook{
+			{Key: cacher.Expire, Fnc: func(key string, data any) {
+				expired <- data.(cacher.Event)
+			}},
+		},
+	})
+	defer cache.Close()
+
+	require.Nil(t, cache.Set("a", "1", cacher.StoreOptions{Ttl: time.Second}))
+	select {
+	case event := <-expired:
+		require.Equal(t, "a", event.Key)
+		require.Equal(t, cacher.ReasonTtl, event.Reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no expire event")
+	}
+}
+
+type failingStore struct {
+	cacher.Store
+	err error
+}
+
+func (s *failingStore) Set(ctx context.Context, key string, value []byte, opts ...cacher.StoreOptions) error {
+	return s.err
+}
+
+func Test_TypedHooks(t *testing.T) {
+	type User struct {
+		Name  string
+		Email string
+	}
+
+	var untyped []string
+	cache := cacher.NewSchema[User](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Hooks: []cacher.Hook{
+			{Key: cacher.BeforeSet, Fnc: func(key string, data any) {
+				untyped = append(untyped, data.(User).Email)
+			}},
+		},
+	})
+	cache.OnBeforeSet(func(ctx context.Context, key string, v *User) error {
+		if v.Name == "" {
+			return errors.New("name required")
+		}
+		v.Email = "redacted"
+		return nil
+	})
+	var read []User
+	cache.OnAfterGet(func(ctx context.Context, key string, v User) {
+		read = append(read, v)
+	})
+	var deleted []string
+	cache.OnAfterDelete(func(ctx context.Context, key string) {
+		deleted = append(deleted, key)
+	})
+
+	require.Nil(t, cache.Set("a", User{Name: "a", Email: "a@example.com"}))
+	require.Equal(t, []string{"a@example.com"}, untyped)
+
+	user, err := cache.Get("a")
+	require.Nil(t, err)
+	require.Equal(t, User{Name: "a", Email: "redacted"}, user)
+	require.Equal(t, []User{user}, read)
+
+	require.EqualError(t, cache.Set("b", User{}), "name required")
+	_, err = cache.Get("b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	require.Nil(t, cache.MSet(cacher.Params[User]{Key: "c", Value: User{Name: "c", Email: "c@example.com"}}))
+	user, err = cache.Get("c")
+	require.Nil(t, err)
+	require.Equal(t, "redacted", user.Email)
+
+	require.Nil(t, cache.Delete("a"))
+	require.Equal(t, []string{"a"}, deleted)
+
+	cache.OnBeforeGet(func(ctx context.Context, key string) error {
+		panic("boom")
+	})
+	_, err = cache.Get("c")
+	require.EqualError(t, err, "cacher: before_get hook panicked: boom")
+}
@@ -0,0 +1,363 @@
+package cacher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinh-tinh/tinhtinh/v2/core"
+)
+
+// Headers set on the responses ResponseCache handles.
+const (
+	HeaderXCache = "X-Cache"
+	HeaderAge    = "Age"
+)
+
+// ResponseCacheOptions configures ResponseCache.
+type ResponseCacheOptions struct {
+	// Ttl of responses without a max-age or CacheTTL, 0 keeps the config Ttl.
+	Ttl time.Duration
+	// Methods cached, GET and HEAD when empty.
+	Methods []string
+	// Vary lists the request headers that select between responses.
+	Vary []string
+	// Identity returns the user a response belongs to, so users never see
+	// each other's responses. Without it private responses are not cached,
+	// and requests with an Authorization header or cookies bypass the cache
+	// unless the response is public or has an s-maxage.
+	Identity func(ctx core.Ctx) string
+	// Namespace of the cached responses, "http" when empty.
+	Namespace string
+}
+
+// Route metadata read by ResponseCache, see CacheTTL, CacheKey, CacheStore
+// and NoCache.
+const (
+	CACHE_TTL     = "cacher_ttl"
+	CACHE_KEY     = "cacher_key"
+	CACHE_STORE   = "cacher_store"
+	CACHE_DISABLE = "cacher_disable"
+)
+
+// KeyFnc builds the key of a cached response from the request.
+type KeyFnc func(ctx core.Ctx) string
+
+// CacheTTL sets the ttl of the responses of a route without a max-age.
+func CacheTTL(ttl time.Duration) *core.Metadata {
+	return core.SetMetadata(CACHE_TTL, ttl)
+}
+
+// CacheKey replaces the method, path and query in the keys of a route.
+// The Vary headers and identity still select between responses.
+func CacheKey(fnc KeyFnc) *core.Metadata {
+	return core.SetMetadata(CACHE_KEY, fnc)
+}
+
+// CacheStore caches the responses of a route in the store registered
+// under name with RegisterMulti. Routes naming a store that is not
+// registered are not cached.
+func CacheStore(name string) *core.Metadata {
+	return core.SetMetadata(CACHE_STORE, name)
+}
+
+// NoCache turns off response caching for a route.
+func NoCache() *core.Metadata {
+	return core.SetMetadata(CACHE_DISABLE, true)
+}
+
+type cachedResponse struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Created time.Time   `json:"created"`
+	// Vary lists the request headers the response varies on, in an entry
+	// pointing to the responses cached for each of their values.
+	Vary []string `json:"vary,omitempty"`
+}
+
+// hopHeaders are not replayed from the cache.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Date", HeaderAge, HeaderXCache,
+}
+
+// ResponseCache returns a middleware caching the status, headers and body
+// of successful responses in the config store. It honours Cache-Control
+// on both the request and the response as well as the response Vary, and
+// sets the Age and X-Cache headers. Every response is tagged with its
+// path for InvalidateTags.
+func (c *Config) ResponseCache(opts ...ResponseCacheOptions) core.Middleware {
+	var opt ResponseCacheOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if len(opt.Methods) == 0 {
+		opt.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if opt.Namespace == "" {
+		opt.Namespace = "http"
+	}
+
+	var mu sync.Mutex
+	stores := map[string]*Schema[cachedResponse]{}
+	responses := func(name string, config *Config) *Schema[cachedResponse] {
+		mu.Lock()
+		defer mu.Unlock()
+		if schema, ok := stores[name]; ok {
+			return schema
+		}
+		cfg := *config
+		cfg.Codec = nil
+		cfg.Namespace = joinKey(config.Namespace, opt.Namespace)
+		stores[name] = NewSchema[cachedResponse](cfg)
+		return stores[name]
+	}
+
+	return func(ctx core.Ctx) error {
+		r := ctx.Req()
+		reqCc := parseCacheControl(r.Header.Get("Cache-Control"))
+		if !slices.Contains(opt.Methods, r.Method) || reqCc.has("no-store") ||
+			core.Reflector[bool](CACHE_DISABLE, ctx) {
+			return ctx.Next()
+		}
+
+		schema := responses("", c)
+		if name := core.Reflector[string](CACHE_STORE, ctx); name != "" {
+			config, ok := ctx.Ref(core.Provide(name)).(*Config)
+			if !ok {
+				return ctx.Next()
+			}
+			schema = responses(name, config)
+		}
+		schema = schema.WithCtx(r.Context())
+
+		identity := ""
+		if opt.Identity != nil {
+			identity = opt.Identity(ctx)
+		}
+		shared := identity == "" &&
+			(r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "")
+		key := r.Method + ":" + r.URL.EscapedPath()
+		if query := r.URL.Query(); len(query) > 0 {
+			key += "?" + query.Encode()
+		}
+		if fnc := core.Reflector[KeyFnc](CACHE_KEY, ctx); fnc != nil {
+			key = fnc(ctx)
+		}
+		base := key
+		key = varyKey(base, r, opt.Vary, identity)
+
+		if !reqCc.has("no-cache") && !shared {
+			cached, err := schema.Get(key)
+			if err == nil && len(cached.Vary) > 0 {
+				cached, err = schema.Get(varyKey(base, r, slices.Concat(opt.Vary, cached.Vary), identity))
+			}
+			if err == nil {
+				age := time.Since(cached.Created)
+				maxAge, ok := reqCc.seconds("max-age")
+				if !ok || age <= maxAge {
+					return replay(ctx.Res(), cached, age)
+				}
+			}
+		}
+
+		w := ctx.Res()
+		w.Header().Set(HeaderXCache, "MISS")
+		rec := &responseRecorder{ResponseWriter: w}
+		ctx.SetCtx(rec, r)
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+
+		ttl, ok := responseTtl(rec, identity != "", shared)
+		if !ok {
+			return nil
+		}
+		varied, ok := responseVary(w.Header(), opt.Vary)
+		if !ok {
+			return nil
+		}
+		if ttl <= 0 {
+			ttl = core.Reflector[time.Duration](CACHE_TTL, ctx)
+		}
+		if ttl <= 0 {
+			ttl = opt.Ttl
+		}
+		storeOpts := StoreOptions{Ttl: ttl, Tags: []string{PathTag(r.URL.Path)}}
+		if len(varied) > 0 {
+			schema.Set(key, cachedResponse{Vary: varied, Created: time.Now()}, storeOpts)
+			key = varyKey(base, r, slices.Concat(opt.Vary, varied), identity)
+		}
+		schema.Set(key, cachedResponse{
+			Status:  rec.status,
+			Header:  responseHeader(w.Header()),
+			Body:    rec.body.Bytes(),
+			Created: time.Now(),
+		}, storeOpts)
+		return nil
+	}
+}
+
+// PathTag is the tag ResponseCache puts on the responses cached for path.
+func PathTag(path string) string {
+	return "http:" + path
+}
+
+// varyKey appends a hash of the Vary headers and identity to key when
+// there are any.
+func varyKey(key string, r *http.Request, vary []string, identity string) string {
+	var varying strings.Builder
+	for _, name := range vary {
+		varying.WriteString(strings.ToLower(name))
+		varying.WriteByte('=')
+		varying.WriteString(r.Header.Get(name))
+		varying.WriteByte('\n')
+	}
+	if identity != "" {
+		varying.WriteString("identity=")
+		varying.WriteString(identity)
+	}
+	if varying.Len() == 0 {
+		return key
+	}
+	sum := sha256.Sum256([]byte(varying.String()))
+	return key + "#" + hex.EncodeToString(sum[:8])
+}
+
+// responseTtl reports whether the recorded response may be cached and for
+// how long its Cache-Control allows, 0 when it does not say. Responses to
+// requests with credentials but no identity are shared between users, so
+// they must be public or have an s-maxage, as RFC 9111 section 3.5 asks
+// of shared caches.
+func responseTtl(rec *responseRecorder, private bool, shared bool) (time.Duration, bool) {
+	if rec.status < 200 || rec.status >= 300 || rec.status == http.StatusPartialContent {
+		return 0, false
+	}
+	header := rec.Header()
+	if header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("no-cache") || cc.has("private") && !private {
+		return 0, false
+	}
+	if shared && !cc.has("public") && !cc.has("s-maxage") {
+		return 0, false
+	}
+	if ttl, ok := cc.seconds("s-maxage"); ok {
+		return ttl, ttl > 0
+	}
+	if ttl, ok := cc.seconds("max-age"); ok {
+		return ttl, ttl > 0
+	}
+	return 0, true
+}
+
+// responseVary returns the request headers in the Vary of a response that
+// vary does not already select on, and false for Vary: *, which no other
+// request matches.
+func responseVary(header http.Header, vary []string) ([]string, bool) {
+	var varied []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return nil, false
+			}
+			if name == "" || slices.ContainsFunc(vary, func(v string) bool {
+				return strings.EqualFold(v, name)
+			}) || slices.Contains(varied, name) {
+				continue
+			}
+			varied = append(varied, name)
+		}
+	}
+	slices.Sort(varied)
+	return varied, true
+}
+
+func responseHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+func replay(w http.ResponseWriter, cached cachedResponse, age time.Duration) error {
+	header := w.Header()
+	for name, values := range cached.Header {
+		header[name] = values
+	}
+	header.Set(HeaderAge, strconv.Itoa(int(age.Seconds())))
+	header.Set(HeaderXCache, "HIT")
+	w.WriteHeader(cached.Status)
+	_, err := w.Write(cached.Body)
+	return err
+}
+
+// responseRecorder passes the response through while keeping a copy.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + ":" + key
+}
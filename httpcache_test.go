@@ -0,0 +1,298 @@
+package cacher_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/tinh-tinh/tinhtinh/v2/core"
+)
+
+func Test_ResponseCache(t *testing.T) {
+	calls := 0
+	userController := func(module core.Module) core.Controller {
+		ctrl := module.NewController("users")
+		ctrl.Use(cacher.Inject(module).ResponseCache(cacher.ResponseCacheOptions{
+			Vary: []string{"Accept-Language"},
+			Identity: func(ctx core.Ctx) string {
+				return ctx.Headers("X-User")
+			},
+		})).Registry()
+
+		ctrl.Get("", func(ctx core.Ctx) error {
+			calls++
+			ctx.Res().Header().Set("X-Served-By", "users")
+			if cc := ctx.Query("cc"); cc != "" {
+				ctx.Res().Header().Set("Cache-Control", cc)
+			}
+			return ctx.JSON(core.Map{"data": calls})
+		})
+		ctrl.Get("missing", func(ctx core.Ctx) error {
+			calls++
+			return ctx.Status(http.StatusNotFound).JSON(core.Map{"data": calls})
+		})
+		return ctrl
+	}
+
+	appModule := func() core.Module {
+		return core.NewModule(core.NewModuleOptions{
+			Imports: []core.Modules{
+				cacher.Register(cacher.Config{
+					Store: cacher.NewInMemory(cacher.StoreOptions{
+						Ttl: 15 * time.Minute,
+					}),
+				}),
+			},
+			Controllers: []core.Controllers{userController},
+		})
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	get := func(path string, header ...string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/"+path, nil)
+		require.Nil(t, err)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := testServer.Client().Do(req)
+		require.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := get("users")
+	require.Equal(t, "MISS", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":1}`, body)
+
+	resp, body = get("users")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "HIT", resp.Header.Get("X-Cache"))
+	require.Equal(t, "0", resp.Header.Get("Age"))
+	require.Equal(t, "users", resp.Header.Get("X-Served-By"))
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, `{"data":1}`, body)
+
+	// The query, Vary headers and identity select other responses.
+	_, body = get("users?page=2")
+	require.Equal(t, `{"data":2}`, body)
+	_, body = get("users", "Accept-Language", "vi")
+	require.Equal(t, `{"data":3}`, body)
+	_, body = get("users", "X-User", "42")
+	require.Equal(t, `{"data":4}`, body)
+	_, body = get("users", "X-User", "42")
+	require.Equal(t, `{"data":4}`, body)
+
+	// Request Cache-Control.
+	resp, body = get("users", "Cache-Control", "no-cache")
+	require.Equal(t, "MISS", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":5}`, body)
+	_, body = get("users")
+	require.Equal(t, `{"data":5}`, body)
+	_, body = get("users", "Cache-Control", "max-age=0")
+	require.Equal(t, `{"data":6}`, body)
+	resp, body = get("users", "Cache-Control", "no-store")
+	require.Equal(t, "", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":7}`, body)
+
+	// Response Cache-Control and status.
+	_, body = get("users?cc=no-store")
+	require.Equal(t, `{"data":8}`, body)
+	_, body = get("users?cc=no-store")
+	require.Equal(t, `{"data":9}`, body)
+	_, body = get("users?cc=private")
+	require.Equal(t, `{"data":10}`, body)
+	_, body = get("users?cc=private")
+	require.Equal(t, `{"data":11}`, body)
+	_, body = get("users?cc=max-age%3D60")
+	require.Equal(t, `{"data":12}`, body)
+	_, body = get("users?cc=max-age%3D60")
+	require.Equal(t, `{"data":12}`, body)
+
+	resp, body = get("users/missing")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, `{"data":13}`, body)
+	_, body = get("users/missing")
+	require.Equal(t, `{"data":14}`, body)
+}
+
+func Test_ResponseCacheShared(t *testing.T) {
+	calls := 0
+	userController := func(module core.Module) core.Controller {
+		ctrl := module.NewController("users")
+		ctrl.Use(cacher.Inject(module).ResponseCache()).Registry()
+
+		ctrl.Get("", func(ctx core.Ctx) error {
+			calls++
+			if cc := ctx.Query("cc"); cc != "" {
+				ctx.Res().Header().Set("Cache-Control", cc)
+			}
+			if vary := ctx.Query("vary"); vary != "" {
+				ctx.Res().Header().Set("Vary", vary)
+			}
+			return ctx.JSON(core.Map{"data": calls})
+		})
+		return ctrl
+	}
+
+	appModule := func() core.Module {
+		return core.NewModule(core.NewModuleOptions{
+			Imports: []core.Modules{
+				cacher.Register(cacher.Config{
+					Store: cacher.NewInMemory(cacher.StoreOptions{
+						Ttl: 15 * time.Minute,
+					}),
+				}),
+			},
+			Controllers: []core.Controllers{userController},
+		})
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	get := func(path string, header ...string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/"+path, nil)
+		require.Nil(t, err)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := testServer.Client().Do(req)
+		require.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return resp, string(body)
+	}
+
+	// Without an identity, credentials bypass the cache and their
+	// responses are only stored when public.
+	_, body := get("users", "Authorization", "Bearer john")
+	require.Equal(t, `{"data":1}`, body)
+	_, body = get("users")
+	require.Equal(t, `{"data":2}`, body)
+	_, body = get("users?x=1", "Cookie", "session=john")
+	require.Equal(t, `{"data":3}`, body)
+	_, body = get("users?x=1")
+	require.Equal(t, `{"data":4}`, body)
+	resp, body := get("users?cc=public", "Authorization", "Bearer john")
+	require.Equal(t, "MISS", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":5}`, body)
+	resp, body = get("users?cc=public")
+	require.Equal(t, "HIT", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":5}`, body)
+
+	// The response Vary selects between responses.
+	_, body = get("users?vary=Accept-Language", "Accept-Language", "en")
+	require.Equal(t, `{"data":6}`, body)
+	_, body = get("users?vary=Accept-Language", "Accept-Language", "vi")
+	require.Equal(t, `{"data":7}`, body)
+	_, body = get("users?vary=Accept-Language", "Accept-Language", "en")
+	require.Equal(t, `{"data":6}`, body)
+	_, body = get("users?vary=Accept-Language", "Accept-Language", "vi")
+	require.Equal(t, `{"data":7}`, body)
+
+	_, body = get("users?vary=*")
+	require.Equal(t, `{"data":8}`, body)
+	_, body = get("users?vary=*")
+	require.Equal(t, `{"data":9}`, body)
+}
+
+func Test_RouteCache(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	tiered := cacher.NewTiered(cacher.TieredOptions{
+		L2: cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute}),
+	})
+
+	calls := 0
+	handler := func(ctx core.Ctx) error {
+		calls++
+		return ctx.JSON(core.Map{"data": calls})
+	}
+	itemController := func(module core.Module) core.Controller {
+		ctrl := module.NewController("items")
+		ctrl.Use(cacher.Inject(module).ResponseCache()).Registry()
+
+		ctrl.Metadata(cacher.CacheTTL(time.Minute)).Get("ttl", handler)
+		ctrl.Metadata(cacher.CacheKey(func(ctx core.Ctx) string {
+			return "items:" + ctx.Query("id")
+		})).Get("key", handler)
+		ctrl.Metadata(cacher.CacheStore(cacher.TIERED)).Get("store", handler)
+		ctrl.Metadata(cacher.CacheStore("unknown")).Get("unknown", handler)
+		ctrl.Metadata(cacher.NoCache()).Get("off", handler)
+		return ctrl
+	}
+
+	appModule := func() core.Module {
+		return core.NewModule(core.NewModuleOptions{
+			Imports: []core.Modules{
+				cacher.Register(cacher.Config{Store: memory}),
+				cacher.RegisterMulti(cacher.Config{Store: tiered}),
+			},
+			Controllers: []core.Controllers{itemController},
+		})
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := testServer.Client().Get(testServer.URL + "/api/items/" + path)
+		require.Nil(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return resp, string(body)
+	}
+	ctx := context.Background()
+
+	_, body := get("ttl")
+	require.Equal(t, `{"data":1}`, body)
+	_, body = get("ttl")
+	require.Equal(t, `{"data":1}`, body)
+	ttl, err := memory.(cacher.TtlStore).TTL(ctx, "http:GET:/api/items/ttl")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, time.Minute)
+
+	_, body = get("key?id=1&page=1")
+	require.Equal(t, `{"data":2}`, body)
+	_, body = get("key?id=1&page=2")
+	require.Equal(t, `{"data":2}`, body)
+	_, err = memory.Get(ctx, "http:items:1")
+	require.Nil(t, err)
+
+	_, body = get("store")
+	require.Equal(t, `{"data":3}`, body)
+	resp, body := get("store")
+	require.Equal(t, "HIT", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":3}`, body)
+	_, err = tiered.Get(ctx, "http:GET:/api/items/store")
+	require.Nil(t, err)
+	_, err = memory.Get(ctx, "http:GET:/api/items/store")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	_, body = get("unknown")
+	require.Equal(t, `{"data":4}`, body)
+	_, body = get("unknown")
+	require.Equal(t, `{"data":5}`, body)
+
+	resp, body = get("off")
+	require.Equal(t, "", resp.Header.Get("X-Cache"))
+	require.Equal(t, `{"data":6}`, body)
+	_, body = get("off")
+	require.Equal(t, `{"data":7}`, body)
+}
@@ -15,7 +15,7 @@ func InjectSchema[M any](ref core.RefProvider) *Schema[M] {
 	if cache == nil {
 		return nil
 	}
-	return NewSchema[M](*cache)
+	return injectSchema[M](cache)
 }
 
 func InjectByStore(ref core.RefProvider, store string) *Config {
@@ -31,5 +31,18 @@ func InjectSchemaByStore[M any](ref core.RefProvider, store string) *Schema[M] {
 	if cache == nil {
 		return nil
 	}
-	return NewSchema[M](*cache)
+	return injectSchema[M](cache)
+}
+
+// injectSchema returns a schema on a registered config. The schemas of a
+// config share one subscription to the store events, which lives as long
+// as the config.
+func injectSchema[M any](config *Config) *Schema[M] {
+	s := newSchema[M](*config)
+	if config.watched == nil {
+		s.unwatch = s.Config.watch()
+		return s
+	}
+	config.watched.Do(func() { s.Config.watch() })
+	return s
 }
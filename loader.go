@@ -0,0 +1,156 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type LoaderFnc[M any] func(ctx context.Context) (M, error)
+
+type KeyLoaderFnc[M any] func(ctx context.Context, key string) (M, error)
+
+type call[M any] struct {
+	wg  sync.WaitGroup
+	val M
+	err error
+}
+
+// flight collapses concurrent loads for the same key into a single call
+// and bounds how many distinct loads may run at once.
+type flight[M any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[M]
+	sem   chan struct{}
+}
+
+func newFlight[M any](limit int) *flight[M] {
+	f := &flight[M]{
+		calls: make(map[string]*call[M]),
+	}
+	if limit > 0 {
+		f.sem = make(chan struct{}, limit)
+	}
+	return f
+}
+
+func (f *flight[M]) do(ctx context.Context, key string, fn func() (M, error)) (M, error) {
+	c, leader := f.join(key)
+	if !leader {
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	f.finish(ctx, key, c, fn)
+	return c.val, c.err
+}
+
+// goDo starts fn in the background unless a call for key is already running.
+func (f *flight[M]) goDo(ctx context.Context, key string, fn func() (M, error)) {
+	c, leader := f.join(key)
+	if !leader {
+		return
+	}
+	go f.finish(ctx, key, c, fn)
+}
+
+func (f *flight[M]) join(key string) (*call[M], bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.calls[key]; ok {
+		return c, false
+	}
+	c := &call[M]{}
+	c.wg.Add(1)
+	f.calls[key] = c
+	return c, true
+}
+
+// finish runs fn for the waiters of c. A panicking fn is recovered and
+// returned to them as an error, so the key can be loaded again.
+func (f *flight[M]) finish(ctx context.Context, key string, c *call[M], fn func() (M, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.val, c.err = *new(M), fmt.Errorf("cacher: loader panicked: %v", r)
+		}
+		c.wg.Done()
+
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+	}()
+	c.val, c.err = f.run(ctx, fn)
+}
+
+func (f *flight[M]) run(ctx context.Context, fn func() (M, error)) (M, error) {
+	if f.sem != nil {
+		select {
+		case f.sem <- struct{}{}:
+			defer func() { <-f.sem }()
+		case <-ctx.Done():
+			return *new(M), ctx.Err()
+		}
+	}
+	return fn()
+}
+
+// GetOrLoad returns the cached value for key, calling loader on a miss and
+// storing its result. Concurrent misses for the same key share one loader
+// call. Loader errors are returned to every waiter and never cached. When
+// writing the loaded value back fails, the value is returned along with
+// the store error.
+func (s *Schema[M]) GetOrLoad(key string, loader LoaderFnc[M], opts ...StoreOptions) (_ M, err error) {
+	s, end := s.trace("get_or_load", key)
+	defer end(&err)
+
+	val, stale, err := s.get(key)
+	if err == nil {
+		if stale {
+			s.revalidate(key, func(ctx context.Context, _ string) (M, error) {
+				return loader(ctx)
+			}, opts...)
+		}
+		return val, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return *new(M), err
+	}
+
+	var setErr error
+	val, err = s.loads.do(s.ctx, s.generateKey(key), func() (M, error) {
+		start := time.Now()
+		data, err := loader(s.ctx)
+		if err != nil {
+			return *new(M), err
+		}
+		setErr = s.set(key, data, time.Since(start), opts...)
+		return data, nil
+	})
+	if err != nil {
+		return *new(M), err
+	}
+	return val, setErr
+}
+
+// SetLoader registers the loader Get uses to refresh stale values in the
+// background when StaleTtl is set.
+func (s *Schema[M]) SetLoader(loader KeyLoaderFnc[M]) {
+	s.loader = loader
+}
+
+// revalidate refreshes key in the background, detached from the
+// cancellation of the schema context since the caller does not wait for
+// it. Nobody sees its result, so failures go to the Error hooks.
+func (s *Schema[M]) revalidate(key string, loader KeyLoaderFnc[M], opts ...StoreOptions) {
+	ctx := context.WithoutCancel(s.ctx)
+	s = s.WithCtx(ctx)
+	s.loads.goDo(ctx, s.generateKey(key), func() (M, error) {
+		start := time.Now()
+		data, err := loader(ctx, key)
+		if err != nil {
+			return *new(M), s.fail(key, ReasonLoad, err)
+		}
+		return data, s.set(key, data, time.Since(start), opts...)
+	})
+}
@@ -0,0 +1,289 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_GetOrLoad(t *testing.T) {
+	var afterSet int32
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "users",
+		Hooks: []cacher.Hook{
+			{Key: cacher.AfterSet, Fnc: func(key string, data interface{}) {
+				atomic.AddInt32(&afterSet, 1)
+			}},
+		},
+	})
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "John", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := cache.GetOrLoad("1", loader)
+			require.Nil(t, err)
+			require.Equal(t, "John", data)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Equal(t, int32(1), atomic.LoadInt32(&afterSet))
+
+	data, err := cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+}
+
+func Test_GetOrLoadError(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+	})
+
+	errLoad := errors.New("db down")
+	_, err := cache.GetOrLoad("1", func(ctx context.Context) (string, error) {
+		return "", errLoad
+	})
+	require.ErrorIs(t, err, errLoad)
+
+	_, err = cache.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	data, err := cache.GetOrLoad("1", func(ctx context.Context) (string, error) {
+		return "Jane", nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, "Jane", data)
+}
+
+func Test_GetOrLoadMaxLoads(t *testing.T) {
+	cache := cacher.NewSchema[int](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		MaxLoads: 2,
+	})
+
+	var running, peak int32
+	loader := func(ctx context.Context) (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, err := cache.GetOrLoad(key, loader)
+			require.Nil(t, err)
+		}(key)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+}
+
+func Test_StaleWhileRevalidate(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store:    cacher.NewInMemory(cacher.StoreOptions{}),
+		Ttl:      1 * time.Second,
+		StaleTtl: 5 * time.Second,
+	})
+
+	var calls int32
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "Jane", nil
+	})
+
+	err := cache.Set("1", "John")
+	require.Nil(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		data, err := cache.Get("1")
+		require.Nil(t, err)
+		require.Equal(t, "John", data)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	data, err := cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", data)
+}
+
+func Test_StaleGetOrLoad(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		StaleTtl: 5 * time.Second,
+	})
+
+	err := cache.Set("1", "John", cacher.StoreOptions{Ttl: 1 * time.Second})
+	require.Nil(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+	data, err := cache.GetOrLoad("1", func(ctx context.Context) (string, error) {
+		return "Jane", nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+
+	time.Sleep(100 * time.Millisecond)
+	data, err = cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", data)
+}
+
+func Test_StaleRevalidateDetached(t *testing.T) {
+	failures := make(chan cacher.Event, 1)
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store:    cacher.NewInMemory(cacher.StoreOptions{}),
+		Ttl:      1 * time.Second,
+		StaleTtl: 5 * time.Second,
+		Hooks: []cacher.Hook{
+			{Key: cacher.Error, Fnc: func(key string, data interface{}) {
+				failures <- data.(cacher.Event)
+			}},
+		},
+	})
+	require.Nil(t, cache.Set("1", "John"))
+	require.Nil(t, cache.Set("2", "Jane"))
+	time.Sleep(1100 * time.Millisecond)
+
+	// the refresh outlives the request that triggered it
+	ctx, cancel := context.WithCancel(context.Background())
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "Jim", ctx.Err()
+	})
+	data, err := cache.WithCtx(ctx).Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+	cancel()
+
+	time.Sleep(100 * time.Millisecond)
+	data, err = cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Jim", data)
+
+	// failed refreshes are reported
+	errLoad := errors.New("db down")
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		return "", errLoad
+	})
+	_, err = cache.Get("2")
+	require.Nil(t, err)
+	select {
+	case event := <-failures:
+		require.Equal(t, cacher.ReasonLoad, event.Reason)
+		require.ErrorIs(t, event.Err, errLoad)
+	case <-time.After(time.Second):
+		t.Fatal("no error event")
+	}
+}
+
+func Test_StaleDeadline(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		StaleTtl: 1 * time.Second,
+	})
+
+	err := cache.Set("1", "John", cacher.StoreOptions{Ttl: 1 * time.Second})
+	require.Nil(t, err)
+
+	time.Sleep(2100 * time.Millisecond)
+	_, err = cache.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+func Test_XFetch(t *testing.T) {
+	cache := cacher.NewSchema[int](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Ttl:       1 * time.Minute,
+		Recompute: &cacher.XFetch{Beta: 1e6},
+	})
+
+	var calls int32
+	loader := func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return int(atomic.AddInt32(&calls, 1)), nil
+	}
+
+	data, err := cache.GetOrLoad("1", loader)
+	require.Nil(t, err)
+	require.Equal(t, 1, data)
+
+	data, err = cache.GetOrLoad("1", loader)
+	require.Nil(t, err)
+	require.Equal(t, 2, data)
+
+	err = cache.Set("2", 10)
+	require.Nil(t, err)
+	data, err = cache.Get("2")
+	require.Nil(t, err)
+	require.Equal(t, 10, data)
+}
+
+func Test_GetOrLoadPanic(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.GetOrLoad("1", func(ctx context.Context) (string, error) {
+				time.Sleep(20 * time.Millisecond)
+				panic("db driver bug")
+			})
+			require.ErrorContains(t, err, "db driver bug")
+		}()
+	}
+	wg.Wait()
+
+	data, err := cache.GetOrLoad("1", func(ctx context.Context) (string, error) {
+		return "John", nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+}
@@ -0,0 +1,161 @@
+package cacher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLocked is returned by TryLock when another owner holds the lock.
+	ErrLocked = errors.New("lock is held by another owner")
+	// ErrLockNotHeld is returned when releasing or extending a lock that
+	// expired or was taken over.
+	ErrLockNotHeld = errors.New("lock is not held")
+)
+
+const (
+	lockPrefix = "lock:"
+	lockRetry  = 50 * time.Millisecond
+)
+
+// Lease is a held lock. It is renewed in the background every third of
+// its ttl until Unlock is called or a renewal finds the lock lost.
+type Lease struct {
+	store LockStore
+	key   string
+	owner string
+	token int64
+
+	mu   sync.Mutex
+	ttl  time.Duration
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Lock acquires the lock name on store, waiting until it is free or ctx
+// is done.
+func Lock(ctx context.Context, store Store, name string, ttl time.Duration) (*Lease, error) {
+	for {
+		lease, err := TryLock(ctx, store, name, ttl)
+		if !errors.Is(err, ErrLocked) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+// TryLock acquires the lock name on store or returns ErrLocked at once.
+func TryLock(ctx context.Context, store Store, name string, ttl time.Duration) (*Lease, error) {
+	locker, ok := As[LockStore](store)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if ttl <= 0 {
+		return nil, errors.New("lock ttl must be positive")
+	}
+
+	owner, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	key := lockPrefix + name
+	token, err := locker.Acquire(ctx, key, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, ErrLocked
+	}
+
+	lease := &Lease{
+		store: locker,
+		key:   key,
+		owner: owner,
+		token: token,
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go lease.renew()
+	return lease, nil
+}
+
+// Token returns the fencing token of the lease. Tokens grow with every
+// acquisition of a lock, so a resource can reject writes carrying a token
+// older than the last one it saw.
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Done is closed once the lease is released or lost.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Extend resets the ttl of the lock, later renewals keep the new ttl.
+func (l *Lease) Extend(ctx context.Context, ttl time.Duration) error {
+	err := l.store.Extend(ctx, l.key, l.owner, ttl)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.ttl = ttl
+	l.mu.Unlock()
+	return nil
+}
+
+// Unlock stops renewing the lease and releases the lock if it is still
+// held by this lease.
+func (l *Lease) Unlock(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+	return l.store.Release(ctx, l.key, l.owner)
+}
+
+func (l *Lease) renew() {
+	defer close(l.done)
+
+	ttl := l.getTtl()
+	timer := time.NewTimer(ttl / 3)
+	defer timer.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-timer.C:
+		}
+
+		ttl = l.getTtl()
+		err := l.store.Extend(context.Background(), l.key, l.owner, ttl)
+		if err == nil {
+			renewed = time.Now()
+		} else if errors.Is(err, ErrLockNotHeld) || time.Since(renewed) >= ttl {
+			return
+		}
+		timer.Reset(ttl / 3)
+	}
+}
+
+func (l *Lease) getTtl() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ttl
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,71 @@
+package cacher_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Lock(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	holders, tokens := 0, []int64{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := cacher.Lock(ctx, store, "job", time.Second)
+			require.Nil(t, err)
+
+			mu.Lock()
+			holders++
+			require.Equal(t, 1, holders)
+			tokens = append(tokens, lease.Token())
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			require.Nil(t, lease.Unlock(ctx))
+		}()
+	}
+	wg.Wait()
+	require.ElementsMatch(t, []int64{1, 2, 3, 4, 5}, tokens)
+}
+
+func Test_LockRenew(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{})
+	ctx := context.Background()
+
+	lease, err := cacher.TryLock(ctx, store, "job", 300*time.Millisecond)
+	require.Nil(t, err)
+
+	time.Sleep(time.Second)
+	_, err = cacher.TryLock(ctx, store, "job", time.Second)
+	require.ErrorIs(t, err, cacher.ErrLocked)
+
+	timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = cacher.Lock(timeout, store, "job", time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Nil(t, lease.Unlock(ctx))
+	<-lease.Done()
+	require.ErrorIs(t, lease.Extend(ctx, time.Second), cacher.ErrLockNotHeld)
+
+	next, err := cacher.TryLock(ctx, store, "job", time.Second)
+	require.Nil(t, err)
+	require.Equal(t, lease.Token()+1, next.Token())
+	require.Nil(t, next.Unlock(ctx))
+
+	_, err = cacher.TryLock(ctx, struct{ cacher.Store }{store}, "job", time.Second)
+	require.ErrorIs(t, err, cacher.ErrNotSupported)
+}
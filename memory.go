@@ -3,6 +3,8 @@ package cacher
 import (
 	"context"
 	"errors"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +22,9 @@ func NewInMemory(opt StoreOptions) Store {
 		maxItems: opt.MaxItems,
 		data:     make(map[string]item, opt.MaxItems),
 		keys:     make([]string, 0, opt.MaxItems),
+		tags:     make(map[string]map[string]struct{}),
+		locks:    make(map[string]lock),
+		handlers: make(map[int]func(HookKey, Event)),
 	}
 	era.StartTimeStampUpdater()
 	go memory.gc(1 * time.Second)
@@ -29,6 +34,9 @@ func NewInMemory(opt StoreOptions) Store {
 type item struct {
 	v interface{}
 	e uint32
+	t []string
+	// n is the version of the value, bumped on every write.
+	n uint64
 }
 
 type Memory struct {
@@ -37,6 +45,18 @@ type Memory struct {
 	data     map[string]item
 	maxItems int
 	keys     []string
+	tags     map[string]map[string]struct{}
+	version  uint64
+	// locks outlive Clear so fencing tokens keep growing.
+	locks    map[string]lock
+	handlers map[int]func(HookKey, Event)
+	handler  int
+}
+
+type lock struct {
+	owner string
+	token int64
+	exp   time.Time
 }
 
 func (m *Memory) Name() string {
@@ -45,31 +65,58 @@ func (m *Memory) Name() string {
 
 func (m *Memory) Set(ctx context.Context, key string, val []byte, opts ...StoreOptions) error {
 	// Handler
+	i := m.item(val, opts)
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.set(key, i)
+	return nil
+}
+
+// item builds the item stored for val with the given options.
+func (m *Memory) item(val []byte, opts []StoreOptions) item {
 	var exp uint32
+	var tags []string
 	if len(opts) > 0 && opts[0].Ttl != 0 {
 		exp = uint32(opts[0].Ttl.Seconds()) + era.Timestamp()
 	} else {
 		exp = uint32(m.ttl.Seconds()) + era.Timestamp()
 	}
+	if len(opts) > 0 {
+		tags = opts[0].Tags
+	}
+	return item{e: exp, v: val, t: tags}
+}
 
-	m.Lock()
-	defer m.Unlock()
+// live returns the unexpired item under key, m must be locked.
+func (m *Memory) live(key string) (item, bool) {
+	v, ok := m.data[key]
+	if !ok || v.e != 0 && v.e <= era.Timestamp() {
+		return item{}, false
+	}
+	return v, true
+}
 
-	i := item{e: exp, v: val}
+// set stores i under key, evicting the oldest key when full, m must be locked.
+func (m *Memory) set(key string, i item) {
 	if _, exists := m.data[key]; exists {
-		m.data[key] = i
-		return nil
+		m.remove(key)
+		m.put(key, i)
+		return
 	}
 
 	if m.maxItems > 0 && len(m.data) >= m.maxItems {
 		// evict an item
 		evictKey := m.keys[0]
-		delete(m.data, evictKey)
+		if _, ok := m.data[evictKey]; ok {
+			m.remove(evictKey)
+			m.emit(Evict, Event{Key: evictKey, Reason: ReasonCapacity})
+		}
 		m.keys = m.keys[1:]
 	}
-	m.data[key] = i
+	m.put(key, i)
 	m.keys = append(m.keys, key)
-	return nil
 }
 
 func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
@@ -92,22 +139,345 @@ func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
 func (m *Memory) Delete(ctx context.Context, key string) error {
 	// Handler
 	m.Lock()
-	delete(m.data, key)
+	m.remove(key)
 	m.Unlock()
 
 	return nil
 }
 
+func (m *Memory) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := m.Get(ctx, key)
+		if err != nil && err != ErrKeyNotFound {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+func (m *Memory) MSet(ctx context.Context, params ...StoreParams) error {
+	for _, param := range params {
+		if err := m.Set(ctx, param.Key, param.Value, param.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MDelete(ctx context.Context, keys ...string) error {
+	m.Lock()
+	for _, key := range keys {
+		m.remove(key)
+	}
+	m.Unlock()
+	return nil
+}
+
 func (m *Memory) Clear(ctx context.Context) error {
 	md := make(map[string]item)
 	m.Lock()
 	m.data = md
+	m.tags = make(map[string]map[string]struct{})
 	m.Unlock()
 	return nil
 }
 
+func (m *Memory) InvalidateTags(ctx context.Context, tags ...string) error {
+	m.Lock()
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			m.remove(key)
+		}
+		delete(m.tags, tag)
+	}
+	m.Unlock()
+	return nil
+}
+
+// put stores i under key and indexes its tags, m must be locked.
+func (m *Memory) put(key string, i item) {
+	m.version++
+	i.n = m.version
+	m.data[key] = i
+	for _, tag := range i.t {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove deletes key and drops it from its tags, m must be locked.
+func (m *Memory) remove(key string) {
+	i, ok := m.data[key]
+	if !ok {
+		return
+	}
+	delete(m.data, key)
+	for _, tag := range i.t {
+		delete(m.tags[tag], key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
+
+func (m *Memory) IncrBy(ctx context.Context, key string, delta int64, opts ...StoreOptions) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	ts := era.Timestamp()
+	if v, ok := m.data[key]; ok && (v.e == 0 || v.e > ts) {
+		val, _ := v.v.([]byte)
+		n, err := strconv.ParseInt(string(val), 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+		n += delta
+		m.version++
+		v.v = []byte(strconv.FormatInt(n, 10))
+		v.n = m.version
+		m.data[key] = v
+		return n, nil
+	}
+
+	ttl := m.ttl
+	if len(opts) > 0 && opts[0].Ttl != 0 {
+		ttl = opts[0].Ttl
+	}
+	m.set(key, item{e: uint32(ttl.Seconds()) + ts, v: []byte(strconv.FormatInt(delta, 10))})
+	return delta, nil
+}
+
+func (m *Memory) SetNX(ctx context.Context, key string, val []byte, opts ...StoreOptions) (bool, error) {
+	i := m.item(val, opts)
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.live(key); ok {
+		return false, nil
+	}
+	m.set(key, i)
+	return true, nil
+}
+
+func (m *Memory) Replace(ctx context.Context, key string, val []byte, opts ...StoreOptions) (bool, error) {
+	i := m.item(val, opts)
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.live(key); !ok {
+		return false, nil
+	}
+	m.set(key, i)
+	return true, nil
+}
+
+func (m *Memory) GetVersion(ctx context.Context, key string) ([]byte, Version, error) {
+	m.RLock()
+	v, ok := m.live(key)
+	m.RUnlock()
+
+	if !ok {
+		return nil, nil, ErrKeyNotFound
+	}
+	val, ok := v.v.([]byte)
+	if !ok {
+		return nil, nil, errors.New("value save is not supported")
+	}
+	return val, v.n, nil
+}
+
+func (m *Memory) CompareAndSwap(ctx context.Context, key string, val []byte, version Version, opts ...StoreOptions) error {
+	i := m.item(val, opts)
+
+	m.Lock()
+	defer m.Unlock()
+
+	v, ok := m.live(key)
+	if !ok || v.n != version {
+		return ErrConflict
+	}
+	m.set(key, i)
+	return nil
+}
+
+func (m *Memory) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	l := m.locks[key]
+	if l.owner != "" && time.Now().Before(l.exp) {
+		return 0, nil
+	}
+	l = lock{owner: owner, token: l.token + 1, exp: time.Now().Add(ttl)}
+	m.locks[key] = l
+	return l.token, nil
+}
+
+func (m *Memory) Release(ctx context.Context, key string, owner string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	l, err := m.held(key, owner)
+	if err != nil {
+		return err
+	}
+	l.owner = ""
+	m.locks[key] = l
+	return nil
+}
+
+func (m *Memory) Extend(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	l, err := m.held(key, owner)
+	if err != nil {
+		return err
+	}
+	l.exp = time.Now().Add(ttl)
+	m.locks[key] = l
+	return nil
+}
+
+// held returns the lock under key if owner holds it, m must be locked.
+func (m *Memory) held(key string, owner string) (lock, error) {
+	l := m.locks[key]
+	if l.owner != owner || !time.Now().Before(l.exp) {
+		return lock{}, ErrLockNotHeld
+	}
+	return l, nil
+}
+
+func (m *Memory) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.RLock()
+	v, ok := m.data[key]
+	m.RUnlock()
+
+	ts := era.Timestamp()
+	if !ok || v.e != 0 && v.e <= ts {
+		return 0, ErrKeyNotFound
+	}
+	if v.e == 0 {
+		return NoTtl, nil
+	}
+	return time.Duration(v.e-ts) * time.Second, nil
+}
+
+func (m *Memory) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	m.RLock()
+	v, ok := m.data[key]
+	m.RUnlock()
+
+	ts := era.Timestamp()
+	if !ok || v.e != 0 && v.e <= ts {
+		return nil, 0, ErrKeyNotFound
+	}
+	val, ok := v.v.([]byte)
+	if !ok {
+		return nil, 0, errors.New("value save is not supported")
+	}
+	if v.e == 0 {
+		return val, NoTtl, nil
+	}
+	return val, time.Duration(v.e-ts) * time.Second, nil
+}
+
+func (m *Memory) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return m.expire(key, uint32(ttl.Seconds())+era.Timestamp())
+}
+
+func (m *Memory) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return m.expire(key, uint32(at.Unix()))
+}
+
+func (m *Memory) Persist(ctx context.Context, key string) error {
+	return m.expire(key, 0)
+}
+
+// Touch resets the ttl of key to the store ttl, and keeps the key for
+// good when the store has none, like the other stores.
+func (m *Memory) Touch(ctx context.Context, key string) error {
+	if m.ttl <= 0 {
+		return m.Persist(ctx, key)
+	}
+	return m.expire(key, uint32(m.ttl.Seconds())+era.Timestamp())
+}
+
+func (m *Memory) expire(key string, exp uint32) error {
+	m.Lock()
+	defer m.Unlock()
+
+	v, ok := m.data[key]
+	if !ok || v.e != 0 && v.e <= era.Timestamp() {
+		return ErrKeyNotFound
+	}
+	v.e = exp
+	m.data[key] = v
+	return nil
+}
+
+func (m *Memory) Keys(ctx context.Context, prefix string) ([]string, error) {
+	ts := era.Timestamp()
+	keys := []string{}
+	m.RLock()
+	for key, v := range m.data {
+		if strings.HasPrefix(key, prefix) && (v.e == 0 || v.e > ts) {
+			keys = append(keys, key)
+		}
+	}
+	m.RUnlock()
+	return keys, nil
+}
+
+func (m *Memory) Count(ctx context.Context, prefix string) (int, error) {
+	keys, err := m.Keys(ctx, prefix)
+	return len(keys), err
+}
+
+func (m *Memory) ClearPrefix(ctx context.Context, prefix string) error {
+	m.Lock()
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(key)
+		}
+	}
+	m.Unlock()
+	return nil
+}
+
+// Subscribe reports the keys evicted when the store is full and the keys
+// gc removes once expired. Handlers run in their own goroutine.
+func (m *Memory) Subscribe(handler func(hook HookKey, event Event)) func() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.handler++
+	id := m.handler
+	m.handlers[id] = handler
+	return func() {
+		m.Lock()
+		delete(m.handlers, id)
+		m.Unlock()
+	}
+}
+
+// emit hands event to the subscribed handlers, m must be locked.
+func (m *Memory) emit(hook HookKey, event Event) {
+	for _, handler := range m.handlers {
+		go handler(hook, event)
+	}
+}
+
 func (m *Memory) gc(sleep time.Duration) {
-	ticker := time.NewTimer(sleep)
+	ticker := time.NewTicker(sleep)
 	defer ticker.Stop()
 	var expired []string
 	for range ticker.C {
@@ -124,7 +494,8 @@ func (m *Memory) gc(sleep time.Duration) {
 		for i := range expired {
 			v := m.data[expired[i]]
 			if v.e != 0 && v.e <= ts {
-				delete(m.data, expired[i])
+				m.remove(expired[i])
+				m.emit(Expire, Event{Key: expired[i], Reason: ReasonTtl})
 			}
 		}
 		m.Unlock()
@@ -0,0 +1,311 @@
+package cacher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bucket upper bounds of the latency, in seconds, and size, in bytes,
+// histograms.
+var (
+	LatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+	SizeBuckets    = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// DefaultMetrics is the collector served by MetricsHandler.
+var DefaultMetrics = NewMetrics()
+
+// Metrics collects the hits, misses, evictions, errors, latencies and value
+// sizes of the schemas it is set on, per store and namespace. Samples are
+// recorded with atomics, so schemas never wait on each other.
+type Metrics struct {
+	// series maps each metricKey to its *metricSeries.
+	series sync.Map
+}
+
+type metricKey struct {
+	store     string
+	namespace string
+}
+
+type metricSeries struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	// evictions and errors map reasons to *atomic.Uint64, latency and
+	// sizes map operations to *histogram.
+	evictions sync.Map
+	errors    sync.Map
+	latency   sync.Map
+	sizes     sync.Map
+}
+
+// histogram counts samples per bucket. count is added before the buckets,
+// from the widest down, and read after them, from the narrowest up, so a
+// concurrent read stays cumulative.
+type histogram struct {
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	// sum holds the bits of a float64.
+	sum atomic.Uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// MetricsHandler serves DefaultMetrics in the Prometheus text format. Mount
+// it on a tinhtinh controller with ctrl.Handler.
+func MetricsHandler() http.Handler {
+	return DefaultMetrics
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// get returns the series of store and namespace.
+func (m *Metrics) get(store, namespace string) *metricSeries {
+	return loadOrNew(&m.series, metricKey{store: store, namespace: namespace}, func() *metricSeries {
+		return &metricSeries{}
+	})
+}
+
+// loadOrNew returns the value of key in values, storing the one made by
+// fnc when there is none yet.
+func loadOrNew[K comparable, V any](values *sync.Map, key K, fnc func() *V) *V {
+	if v, ok := values.Load(key); ok {
+		return v.(*V)
+	}
+	v, _ := values.LoadOrStore(key, fnc())
+	return v.(*V)
+}
+
+func newCounter() *atomic.Uint64 {
+	return new(atomic.Uint64)
+}
+
+func newHistogram(buckets []float64) func() *histogram {
+	return func() *histogram {
+		return &histogram{counts: make([]atomic.Uint64, len(buckets))}
+	}
+}
+
+// event counts the hits, misses, evictions, expiries and errors.
+func (m *Metrics) event(store, namespace string, hook HookKey, reason string) {
+	series := m.get(store, namespace)
+	switch hook {
+	case Hit:
+		series.hits.Add(1)
+	case Miss:
+		series.misses.Add(1)
+	case Evict, Expire:
+		loadOrNew(&series.evictions, reason, newCounter).Add(1)
+	case Error:
+		loadOrNew(&series.errors, reason, newCounter).Add(1)
+	}
+}
+
+// observe records how long op took.
+func (m *Metrics) observe(store, namespace, op string, d time.Duration) {
+	series := m.get(store, namespace)
+	h := loadOrNew(&series.latency, op, newHistogram(LatencyBuckets))
+	h.observe(LatencyBuckets, d.Seconds())
+}
+
+// size records the encoded size of a value read or written by op.
+func (m *Metrics) size(store, namespace, op string, n int) {
+	series := m.get(store, namespace)
+	h := loadOrNew(&series.sizes, op, newHistogram(SizeBuckets))
+	h.observe(SizeBuckets, float64(n))
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	h.count.Add(1)
+	for i := len(buckets) - 1; i >= 0 && v <= buckets[i]; i-- {
+		h.counts[i].Add(1)
+	}
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	series := map[metricKey]*metricSeries{}
+	m.series.Range(func(key, value any) bool {
+		series[key.(metricKey)] = value.(*metricSeries)
+		return true
+	})
+	keys := make([]metricKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b metricKey) int {
+		if c := strings.Compare(a.store, b.store); c != 0 {
+			return c
+		}
+		return strings.Compare(a.namespace, b.namespace)
+	})
+
+	out := &metricWriter{w: bufio.NewWriter(w)}
+	out.header("cacher_hits_total", "counter", "Reads that found a value.")
+	for _, key := range keys {
+		out.sample("cacher_hits_total", key.labels(), float64(series[key].hits.Load()))
+	}
+	out.header("cacher_misses_total", "counter", "Reads that found no value.")
+	for _, key := range keys {
+		out.sample("cacher_misses_total", key.labels(), float64(series[key].misses.Load()))
+	}
+	out.header("cacher_hit_ratio", "gauge", "Hits over reads.")
+	for _, key := range keys {
+		hits := series[key].hits.Load()
+		ratio := 0.0
+		if reads := hits + series[key].misses.Load(); reads > 0 {
+			ratio = float64(hits) / float64(reads)
+		}
+		out.sample("cacher_hit_ratio", key.labels(), ratio)
+	}
+	out.header("cacher_evictions_total", "counter", "Values removed before being deleted, by reason.")
+	for _, key := range keys {
+		out.counters("cacher_evictions_total", key, "reason", &series[key].evictions)
+	}
+	out.header("cacher_errors_total", "counter", "Failed operations, by reason.")
+	for _, key := range keys {
+		out.counters("cacher_errors_total", key, "reason", &series[key].errors)
+	}
+	out.header("cacher_operation_duration_seconds", "histogram", "Duration of the schema operations, by op.")
+	for _, key := range keys {
+		out.histograms("cacher_operation_duration_seconds", key, LatencyBuckets, &series[key].latency)
+	}
+	out.header("cacher_value_size_bytes", "histogram", "Encoded size of the values read and written.")
+	for _, key := range keys {
+		out.histograms("cacher_value_size_bytes", key, SizeBuckets, &series[key].sizes)
+	}
+
+	if err := out.w.Flush(); err != nil {
+		return out.n, err
+	}
+	return out.n, out.err
+}
+
+func (k metricKey) labels(extra ...string) string {
+	labels := []string{"store", k.store, "namespace", k.namespace}
+	labels = append(labels, extra...)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+type metricWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *metricWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *metricWriter) header(name, kind, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (w *metricWriter) sample(name, labels string, v float64) {
+	w.printf("%s%s %s\n", name, labels, formatFloat(v))
+}
+
+func (w *metricWriter) counters(name string, key metricKey, label string, values *sync.Map) {
+	counters := snapshot[atomic.Uint64](values)
+	for _, v := range sortedKeys(counters) {
+		w.sample(name, key.labels(label, v), float64(counters[v].Load()))
+	}
+}
+
+func (w *metricWriter) histograms(name string, key metricKey, buckets []float64, values *sync.Map) {
+	histograms := snapshot[histogram](values)
+	for _, op := range sortedKeys(histograms) {
+		h := histograms[op]
+		for i, bound := range buckets {
+			w.sample(name+"_bucket", key.labels("op", op, "le", formatFloat(bound)), float64(h.counts[i].Load()))
+		}
+		count := h.count.Load()
+		w.sample(name+"_bucket", key.labels("op", op, "le", "+Inf"), float64(count))
+		w.sample(name+"_sum", key.labels("op", op), math.Float64frombits(h.sum.Load()))
+		w.sample(name+"_count", key.labels("op", op), float64(count))
+	}
+}
+
+// snapshot copies the entries of a sync.Map keyed by strings.
+func snapshot[V any](values *sync.Map) map[string]*V {
+	m := map[string]*V{}
+	values.Range(func(key, value any) bool {
+		m[key.(string)] = value.(*V)
+		return true
+	})
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// measure records the duration of op since start.
+func (s *Schema[M]) measure(op string, start time.Time) {
+	if s.Metrics != nil {
+		s.Metrics.observe(s.Store.Name(), s.Namespace, op, time.Since(start))
+	}
+}
+
+// measureSize records the encoded size of a value read or written by op.
+func (s *Schema[M]) measureSize(op string, n int) {
+	if s.traced != nil {
+		s.traced.size += n
+	}
+	if s.Metrics != nil {
+		s.Metrics.size(s.Store.Name(), s.Namespace, op, n)
+	}
+}
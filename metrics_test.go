@@ -0,0 +1,121 @@
+package cacher_test
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+	"github.com/tinh-tinh/tinhtinh/v2/core"
+)
+
+func Test_Metrics(t *testing.T) {
+	metrics := cacher.NewMetrics()
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "users",
+		Metrics:   metrics,
+	})
+	defer cache.Close()
+
+	require.Nil(t, cache.Set("a", "1"))
+	_, err := cache.Get("a")
+	require.Nil(t, err)
+	_, err = cache.Get("a")
+	require.Nil(t, err)
+	_, err = cache.Get("b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	require.Nil(t, cache.Delete("a"))
+
+	broken := cacher.NewSchema[string](cacher.Config{
+		Store:   &failingStore{Store: cacher.NewInMemory(cacher.StoreOptions{}), err: errors.New("down")},
+		Metrics: metrics,
+	})
+	require.NotNil(t, broken.Set("a", "1"))
+
+	var b strings.Builder
+	_, err = metrics.WriteTo(&b)
+	require.Nil(t, err)
+	out := b.String()
+
+	labels := `{store="memory_cache_manager",namespace="users"}`
+	require.Contains(t, out, "# TYPE cacher_hits_total counter\n")
+	require.Contains(t, out, "cacher_hits_total"+labels+" 2\n")
+	require.Contains(t, out, "cacher_misses_total"+labels+" 1\n")
+	require.Contains(t, out, "cacher_hit_ratio"+labels+" 0.6666666666666666\n")
+	require.Contains(t, out, `cacher_errors_total{store="memory_cache_manager",namespace="",reason="store"} 1`)
+	require.Contains(t, out, `cacher_operation_duration_seconds_count{store="memory_cache_manager",namespace="users",op="get"} 3`)
+	require.Contains(t, out, `cacher_operation_duration_seconds_bucket{store="memory_cache_manager",namespace="users",op="delete",le="+Inf"} 1`)
+	require.Contains(t, out, `cacher_value_size_bytes_count{store="memory_cache_manager",namespace="users",op="set"} 1`)
+}
+
+func Test_MetricsConcurrent(t *testing.T) {
+	metrics := cacher.NewMetrics()
+	store := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache := cacher.NewSchema[string](cacher.Config{
+				Store:     store,
+				Namespace: "users",
+				Metrics:   metrics,
+			})
+			for j := 0; j < 100; j++ {
+				cache.Get("a")
+				metrics.WriteTo(io.Discard)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	_, err := metrics.WriteTo(&b)
+	require.Nil(t, err)
+	labels := `{store="memory_cache_manager",namespace="users"}`
+	require.Contains(t, b.String(), "cacher_misses_total"+labels+" 800\n")
+	require.Contains(t, b.String(), `cacher_operation_duration_seconds_count{store="memory_cache_manager",namespace="users",op="get"} 800`)
+}
+
+func Test_MetricsHandler(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "handler",
+		Metrics:   cacher.DefaultMetrics,
+	})
+	_, err := cache.Get("a")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	appModule := func() core.Module {
+		module := core.NewModule(core.NewModuleOptions{})
+		ctrl := module.NewController("metrics")
+		ctrl.Handler("", cacher.MetricsHandler())
+		return module
+	}
+
+	app := core.CreateFactory(appModule)
+	app.SetGlobalPrefix("api")
+
+	testServer := httptest.NewServer(app.PrepareBeforeListen())
+	defer testServer.Close()
+
+	resp, err := testServer.Client().Get(testServer.URL + "/api/metrics")
+	require.Nil(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Contains(t, string(body), `cacher_misses_total{store="memory_cache_manager",namespace="handler"} 1`)
+}
@@ -0,0 +1,68 @@
+package cacher
+
+import "reflect"
+
+// StoreMiddleware wraps a store with a layer such as logging, timeouts or
+// a read-only mode.
+type StoreMiddleware func(Store) Store
+
+// Unwrapper is implemented by stores wrapping another store, so As can
+// reach the capabilities of the store below.
+type Unwrapper interface {
+	Unwrap() Store
+}
+
+// Chain wraps store with mw, the first middleware being the outermost.
+// Capability interfaces a middleware does not implement are unsupported
+// by the chain, so their calls cannot skip it, unless the middleware
+// implements Unwrapper to let them through.
+func Chain(store Store, mw ...StoreMiddleware) Store {
+	for i := len(mw) - 1; i >= 0; i-- {
+		store = &layer{Store: mw[i](store), next: store}
+	}
+	return store
+}
+
+// layer is a middleware result along with the store it wraps.
+type layer struct {
+	Store
+	next Store
+}
+
+func (l *layer) Unwrap() Store {
+	return l.next
+}
+
+// As returns the first store implementing T in the chain of store and the
+// stores it wraps. It stops at middlewares not implementing a capability
+// T, except for EventStore and concrete store types.
+func As[T any](store Store) (T, bool) {
+	for store != nil {
+		current := store
+		if l, ok := store.(*layer); ok {
+			current = l.Store
+		}
+		if t, ok := current.(T); ok {
+			return t, true
+		}
+		if current != store && !skips[T](current) {
+			break
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return *new(T), false
+}
+
+// skips reports whether a lookup for T may go past the middleware mw.
+func skips[T any](mw Store) bool {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Interface || t == reflect.TypeFor[EventStore]() {
+		return true
+	}
+	_, ok := mw.(Unwrapper)
+	return ok
+}
@@ -0,0 +1,153 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+var errReadOnly = errors.New("read only")
+
+type readOnly struct {
+	cacher.Store
+	batch cacher.BatchStore
+}
+
+func (r readOnly) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	return r.batch.MGet(ctx, keys...)
+}
+
+func (r readOnly) MDelete(ctx context.Context, keys ...string) error {
+	return r.batch.MDelete(ctx, keys...)
+}
+
+func (r readOnly) Set(ctx context.Context, key string, value []byte, opts ...cacher.StoreOptions) error {
+	return errReadOnly
+}
+
+func (r readOnly) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	return errReadOnly
+}
+
+type countGets struct {
+	cacher.Store
+	gets *int
+}
+
+func (c countGets) Get(ctx context.Context, key string) ([]byte, error) {
+	*c.gets++
+	return c.Store.Get(ctx, key)
+}
+
+// Unwrap lets the capabilities countGets does not implement skip it.
+func (c countGets) Unwrap() cacher.Store {
+	return c.Store
+}
+
+func Test_Chain(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	gets := 0
+	var order []string
+	trace := func(name string) cacher.StoreMiddleware {
+		return func(next cacher.Store) cacher.Store {
+			order = append(order, name)
+			return countGets{Store: next, gets: &gets}
+		}
+	}
+
+	store := cacher.Chain(memory, trace("outer"), trace("inner"))
+	require.Equal(t, []string{"inner", "outer"}, order)
+
+	_, ok := cacher.As[cacher.TtlStore](store)
+	require.True(t, ok)
+	_, ok = cacher.As[cacher.CounterStore](store)
+	require.True(t, ok)
+	_, ok = cacher.As[*cacher.Memory](store)
+	require.True(t, ok)
+	_, ok = cacher.As[cacher.TtlStore](struct{ cacher.Store }{memory})
+	require.False(t, ok)
+
+	cache := cacher.NewSchema[string](cacher.Config{Store: store})
+	require.Nil(t, cache.Set("a", "1"))
+	val, err := cache.Get("a")
+	require.Nil(t, err)
+	require.Equal(t, "1", val)
+	require.Equal(t, 2, gets)
+
+	ttl, err := cache.TTL("a")
+	require.Nil(t, err)
+	require.Greater(t, ttl, time.Minute)
+
+	counter := cacher.NewCounter(cacher.Config{Store: store})
+	n, err := counter.Incr("hits")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), n)
+}
+
+func Test_Middlewares(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	require.Nil(t, memory.Set(context.Background(), "a", []byte(`"1"`)))
+
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: memory,
+		Middlewares: []cacher.StoreMiddleware{
+			func(next cacher.Store) cacher.Store {
+				batch, _ := cacher.As[cacher.BatchStore](next)
+				return readOnly{Store: next, batch: batch}
+			},
+		},
+	})
+	val, err := cache.Get("a")
+	require.Nil(t, err)
+	require.Equal(t, "1", val)
+
+	require.ErrorIs(t, cache.Set("b", "2"), errReadOnly)
+	require.ErrorIs(t, cache.MSet(cacher.Params[string]{Key: "b", Value: "2"}), errReadOnly)
+	_, err = cache.Get("b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+type denyWrites struct {
+	cacher.Store
+}
+
+func (d denyWrites) Set(ctx context.Context, key string, value []byte, opts ...cacher.StoreOptions) error {
+	return errReadOnly
+}
+
+func Test_MiddlewareCapabilities(t *testing.T) {
+	memory := cacher.NewInMemory(cacher.StoreOptions{Ttl: 15 * time.Minute})
+	require.Nil(t, memory.Set(context.Background(), "a", []byte(`"1"`)))
+
+	config := cacher.Config{
+		Store: memory,
+		Middlewares: []cacher.StoreMiddleware{
+			func(next cacher.Store) cacher.Store {
+				return denyWrites{Store: next}
+			},
+		},
+	}
+	cache := cacher.NewSchema[string](config)
+	val, err := cache.Get("a")
+	require.Nil(t, err)
+	require.Equal(t, "1", val)
+
+	// batches and counters cannot skip the middleware
+	require.ErrorIs(t, cache.MSet(cacher.Params[string]{Key: "b", Value: "2"}), errReadOnly)
+	_, err = cache.Get("b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	counter := cacher.NewCounter(config)
+	_, err = counter.IncrBy("hits", 1)
+	require.ErrorIs(t, err, cacher.ErrNotSupported)
+	_, err = memory.Get(context.Background(), "hits")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	require.ErrorIs(t, cache.Expire("a", time.Minute), cacher.ErrNotSupported)
+	_, ok := cacher.As[*cacher.Memory](cache.Store)
+	require.True(t, ok)
+}
@@ -1,6 +1,8 @@
 package cacher
 
 import (
+	"sync"
+
 	"github.com/tinh-tinh/tinhtinh/v2/core"
 )
 
@@ -12,7 +14,7 @@ func Register(config Config) core.Modules {
 
 		cacheModule.NewProvider(core.ProviderOptions{
 			Name:  CACHE_MANAGER,
-			Value: &config,
+			Value: registered(config),
 		})
 		cacheModule.Export(CACHE_MANAGER)
 		return cacheModule
@@ -28,7 +30,7 @@ func RegisterFactory(factory ConfigFactory) core.Modules {
 		config := factory(module)
 		cacheModule.NewProvider(core.ProviderOptions{
 			Name:  CACHE_MANAGER,
-			Value: &config,
+			Value: registered(config),
 		})
 		cacheModule.Export(CACHE_MANAGER)
 		return cacheModule
@@ -42,7 +44,7 @@ func RegisterMulti(configs ...Config) core.Modules {
 		for _, config := range configs {
 			cacheModule.NewProvider(core.ProviderOptions{
 				Name:  core.Provide(config.Store.Name()),
-				Value: &config,
+				Value: registered(config),
 			})
 			cacheModule.Export(core.Provide(config.Store.Name()))
 		}
@@ -60,10 +62,17 @@ func RegisterMultiFactory(factory MultiConfigFactory) core.Modules {
 		for _, config := range configs {
 			cacheModule.NewProvider(core.ProviderOptions{
 				Name:  core.Provide(config.Store.Name()),
-				Value: &config,
+				Value: registered(config),
 			})
 			cacheModule.Export(core.Provide(config.Store.Name()))
 		}
 		return cacheModule
 	}
 }
+
+// registered returns the config to provide, whose injected schemas share
+// one subscription to the store events.
+func registered(config Config) *Config {
+	config.watched = &sync.Once{}
+	return &config
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -275,3 +276,33 @@ func Test_ModuleFactory(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, "John", response.Data)
 }
+
+func Test_InjectSchemaEvents(t *testing.T) {
+	var evicted atomic.Int32
+	module := core.NewModule(core.NewModuleOptions{
+		Imports: []core.Modules{
+			cacher.Register(cacher.Config{
+				Store: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute, MaxItems: 1}),
+				Hooks: []cacher.Hook{
+					{Key: cacher.Evict, Fnc: func(key string, data any) {
+						evicted.Add(1)
+					}},
+				},
+			}),
+		},
+	})
+
+	// injected schemas share a single subscription to the store
+	var schemas []*cacher.Schema[string]
+	for i := 0; i < 3; i++ {
+		schemas = append(schemas, cacher.InjectSchema[string](module))
+	}
+	require.Nil(t, schemas[0].Set("1", "John"))
+	require.Nil(t, schemas[1].Set("2", "Jane"))
+
+	require.Eventually(t, func() bool {
+		return evicted.Load() > 0
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(1), evicted.Load())
+}
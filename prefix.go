@@ -0,0 +1,65 @@
+package cacher
+
+import "strings"
+
+// Clear removes every key in the schema namespace. Without a namespace the
+// whole store is cleared.
+func (s *Schema[M]) Clear() (err error) {
+	s, end := s.trace("clear")
+	defer end(&err)
+
+	if s.Namespace == "" {
+		if err := s.Store.Clear(s.ctx); err != nil {
+			return s.fail("", ReasonStore, err)
+		}
+		s.publish(InvalidateAll)
+		s.emit(Clear, "", ReasonClear, nil)
+		return nil
+	}
+	store, ok := As[PrefixStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := store.ClearPrefix(s.ctx, s.prefix()); err != nil {
+		return s.fail("", ReasonStore, err)
+	}
+	s.publish(InvalidatePrefix, s.prefix())
+	s.emit(Clear, "", ReasonClear, nil)
+	return nil
+}
+
+// Count returns how many keys live in the schema namespace.
+func (s *Schema[M]) Count() (_ int, err error) {
+	s, end := s.trace("count")
+	defer end(&err)
+
+	store, ok := As[PrefixStore](s.Store)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return store.Count(s.ctx, s.prefix())
+}
+
+// Keys returns the keys in the schema namespace, without the namespace.
+func (s *Schema[M]) Keys() (_ []string, err error) {
+	s, end := s.trace("keys")
+	defer end(&err)
+
+	store, ok := As[PrefixStore](s.Store)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	keys, err := store.Keys(s.ctx, s.prefix())
+	if err != nil {
+		return nil, err
+	}
+	prefix := s.prefix()
+	for i := range keys {
+		keys[i] = strings.TrimPrefix(keys[i], prefix)
+	}
+	return keys, nil
+}
+
+func (s *Schema[M]) prefix() string {
+	return s.generateKey("")
+}
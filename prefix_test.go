@@ -0,0 +1,58 @@
+package cacher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_NamespaceClear(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{
+		Ttl: 15 * time.Minute,
+	})
+	users := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Namespace: "users",
+	})
+	posts := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Namespace: "posts",
+	})
+
+	require.Nil(t, users.Set("1", "John"))
+	require.Nil(t, users.Set("2", "Jane"))
+	require.Nil(t, posts.Set("1", "Hello"))
+
+	count, err := users.Count()
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	keys, err := users.Keys()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"1", "2"}, keys)
+
+	err = users.Clear()
+	require.Nil(t, err)
+
+	count, err = users.Count()
+	require.Nil(t, err)
+	require.Zero(t, count)
+
+	data, err := posts.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "Hello", data)
+
+	all := cacher.NewSchema[string](cacher.Config{
+		Store: store,
+	})
+	count, err = all.Count()
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+
+	err = all.Clear()
+	require.Nil(t, err)
+	_, err = posts.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
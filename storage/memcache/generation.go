@@ -0,0 +1,130 @@
+package memcache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	memcache_store "github.com/bradfitz/gomemcache/memcache"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+const genPrefix = "gen:"
+
+// Memcache cannot enumerate keys, so Keys and Count are not supported.
+func (m *Memcache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return nil, cacher.ErrNotSupported
+}
+
+func (m *Memcache) Count(ctx context.Context, prefix string) (int, error) {
+	return 0, cacher.ErrNotSupported
+}
+
+// ClearPrefix bumps the generation counter of prefix, which orphans every
+// key written under it. It requires Options.Generations.
+func (m *Memcache) ClearPrefix(ctx context.Context, prefix string) error {
+	if prefix == "" {
+		return m.Clear(ctx)
+	}
+	if !m.generations {
+		return cacher.ErrNotSupported
+	}
+
+	genKey := genPrefix + prefix
+	_, err := m.client.Increment(genKey, 1)
+	if err != memcache_store.ErrCacheMiss {
+		return err
+	}
+	// a fresh generation already orphans the keys written under it
+	_, err = m.seedGeneration(genKey)
+	return err
+}
+
+// seedGeneration creates the missing generation genKey. It is seeded with
+// the clock, so a generation lost to eviction starts past every value it
+// had and the keys written under it stay orphaned.
+func (m *Memcache) seedGeneration(genKey string) ([]byte, error) {
+	item := &memcache_store.Item{
+		Key:   genKey,
+		Value: []byte(strconv.FormatInt(time.Now().UnixNano(), 10)),
+	}
+	err := m.client.Add(item)
+	if err == memcache_store.ErrNotStored {
+		item, err = m.client.Get(genKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+// key resolves the physical key for key. With generations enabled the
+// generation of every ':' terminated prefix of key is appended to it,
+// creating the generations that are missing.
+func (m *Memcache) key(key string) (string, error) {
+	keys, err := m.keys([]string{key})
+	if err != nil {
+		return "", err
+	}
+	return keys[0], nil
+}
+
+// keys resolves the physical keys for keys with one generation lookup.
+func (m *Memcache) keys(keys []string) ([]string, error) {
+	if !m.generations {
+		return keys, nil
+	}
+
+	var genKeys []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, genKey := range generationKeys(key) {
+			if !seen[genKey] {
+				seen[genKey] = true
+				genKeys = append(genKeys, genKey)
+			}
+		}
+	}
+	if len(genKeys) == 0 {
+		return keys, nil
+	}
+
+	items, err := m.client.GetMulti(genKeys)
+	if err != nil {
+		return nil, err
+	}
+	gens := make(map[string][]byte, len(genKeys))
+	for _, genKey := range genKeys {
+		if item, ok := items[genKey]; ok {
+			gens[genKey] = item.Value
+			continue
+		}
+		gens[genKey], err = m.seedGeneration(genKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	physical := make([]string, len(keys))
+	for i, key := range keys {
+		var b strings.Builder
+		b.WriteString(key)
+		for _, genKey := range generationKeys(key) {
+			b.WriteByte('#')
+			b.Write(gens[genKey])
+		}
+		physical[i] = b.String()
+	}
+	return physical, nil
+}
+
+func generationKeys(key string) []string {
+	var genKeys []string
+	for i := 0; i < len(key); i++ {
+		if key[i] == ':' {
+			genKeys = append(genKeys, genPrefix+key[:i+1])
+		}
+	}
+	return genKeys
+}
@@ -2,6 +2,7 @@ package memcache
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	memcache_store "github.com/bradfitz/gomemcache/memcache"
@@ -11,19 +12,24 @@ import (
 type Options struct {
 	Addr []string
 	Ttl  time.Duration
+	// Generations versions keys by a per prefix counter so ClearPrefix can
+	// drop a namespace. It costs one extra round trip per operation.
+	Generations bool
 }
 
 func New(opt Options) cacher.Store {
 	client := memcache_store.New(opt.Addr...)
 	return &Memcache{
-		client: client,
-		ttl:    opt.Ttl,
+		client:      client,
+		ttl:         opt.Ttl,
+		generations: opt.Generations,
 	}
 }
 
 type Memcache struct {
-	client *memcache_store.Client
-	ttl    time.Duration
+	client      *memcache_store.Client
+	ttl         time.Duration
+	generations bool
 }
 
 func (m *Memcache) Name() string {
@@ -35,7 +41,10 @@ func (m *Memcache) SetOptions(opt cacher.StoreOptions) {
 }
 
 func (m *Memcache) Get(ctx context.Context, key string) ([]byte, error) {
-	// Handler
+	key, err := m.key(key)
+	if err != nil {
+		return nil, err
+	}
 	val, err := m.client.Get(key)
 	if err != nil {
 		if err == memcache_store.ErrCacheMiss {
@@ -43,33 +52,113 @@ func (m *Memcache) Get(ctx context.Context, key string) ([]byte, error) {
 		}
 		return nil, err
 	}
+	if val.Flags&flagTagged != 0 {
+		return m.untagValue(val.Value)
+	}
 
 	return val.Value, nil
 }
 
 func (m *Memcache) Set(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) error {
+	item, err := m.item(key, val, opts)
+	if err != nil {
+		return err
+	}
+	err = m.client.Set(item)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// item builds the memcache item stored for key with the given options.
+func (m *Memcache) item(key string, val []byte, opts []cacher.StoreOptions) (*memcache_store.Item, error) {
 	var ttl time.Duration
-	if len(opts) > 0 {
+	if len(opts) > 0 && opts[0].Ttl > 0 {
 		ttl = opts[0].Ttl
 	} else {
 		ttl = m.ttl
 	}
 
-	err := m.client.Set(&memcache_store.Item{
+	key, err := m.key(key)
+	if err != nil {
+		return nil, err
+	}
+	var flags uint32
+	if len(opts) > 0 && len(opts[0].Tags) > 0 {
+		versions, err := m.tagVersions(opts[0].Tags)
+		if err != nil {
+			return nil, err
+		}
+		val = encodeTagged(versions, val)
+		flags |= flagTagged
+	}
+	return &memcache_store.Item{
 		Key:        key,
 		Value:      val,
-		Expiration: int32(ttl.Seconds()),
-	})
+		Flags:      flags,
+		Expiration: lifetime(ttl),
+	}, nil
+}
+
+func (m *Memcache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	physical, err := m.keys(keys)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	items, err := m.client.GetMulti(physical)
+	if err != nil {
+		return nil, err
 	}
 
+	vals := make([][]byte, len(keys))
+	for i, key := range physical {
+		item, ok := items[key]
+		if !ok {
+			continue
+		}
+		if item.Flags&flagTagged != 0 {
+			vals[i], err = m.untagValue(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		vals[i] = item.Value
+	}
+	return vals, nil
+}
+
+func (m *Memcache) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	for _, param := range params {
+		if err := m.Set(ctx, param.Key, param.Value, param.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memcache) MDelete(ctx context.Context, keys ...string) error {
+	physical, err := m.keys(keys)
+	if err != nil {
+		return err
+	}
+	for _, key := range physical {
+		err := m.client.Delete(key)
+		if err != nil && err != memcache_store.ErrCacheMiss {
+			return err
+		}
+	}
 	return nil
 }
 
 func (m *Memcache) Delete(ctx context.Context, key string) error {
-	// Handler
-	err := m.client.Delete(key)
+	key, err := m.key(key)
+	if err != nil {
+		return err
+	}
+	err = m.client.Delete(key)
 	if err != nil {
 		return err
 	}
@@ -77,6 +166,255 @@ func (m *Memcache) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// IncrBy uses memcache counters, which are unsigned: decrementing stops at
+// 0 and a counter cannot be created with a negative value.
+func (m *Memcache) IncrBy(ctx context.Context, key string, delta int64, opts ...cacher.StoreOptions) (int64, error) {
+	key, err := m.key(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := m.incr(key, delta)
+	if err != memcache_store.ErrCacheMiss {
+		return n, err
+	}
+	if delta < 0 {
+		return 0, cacher.ErrNotInteger
+	}
+
+	ttl := m.ttl
+	if len(opts) > 0 && opts[0].Ttl > 0 {
+		ttl = opts[0].Ttl
+	}
+	err = m.client.Add(&memcache_store.Item{
+		Key:        key,
+		Value:      []byte(strconv.FormatInt(delta, 10)),
+		Expiration: lifetime(ttl),
+	})
+	if err == memcache_store.ErrNotStored {
+		// created concurrently
+		return m.incr(key, delta)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return delta, nil
+}
+
+func (m *Memcache) incr(key string, delta int64) (int64, error) {
+	var n uint64
+	var err error
+	if delta < 0 {
+		n, err = m.client.Decrement(key, uint64(-delta))
+	} else {
+		n, err = m.client.Increment(key, uint64(delta))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+func (m *Memcache) SetNX(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	item, err := m.item(key, val, opts)
+	if err != nil {
+		return false, err
+	}
+	return stored(m.client.Add(item))
+}
+
+func (m *Memcache) Replace(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	item, err := m.item(key, val, opts)
+	if err != nil {
+		return false, err
+	}
+	return stored(m.client.Replace(item))
+}
+
+// GetVersion returns the memcache item, whose cas id CompareAndSwap checks.
+// A value dropped by InvalidateTags reads as nil with its version.
+func (m *Memcache) GetVersion(ctx context.Context, key string) ([]byte, cacher.Version, error) {
+	physical, err := m.key(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	item, err := m.client.Get(physical)
+	if err != nil {
+		if err == memcache_store.ErrCacheMiss {
+			return nil, nil, cacher.ErrKeyNotFound
+		}
+		return nil, nil, err
+	}
+	val := item.Value
+	if item.Flags&flagTagged != 0 {
+		val, err = m.untagValue(item.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return val, item, nil
+}
+
+func (m *Memcache) CompareAndSwap(ctx context.Context, key string, val []byte, version cacher.Version, opts ...cacher.StoreOptions) error {
+	read, ok := version.(*memcache_store.Item)
+	if !ok {
+		return cacher.ErrConflict
+	}
+	item, err := m.item(key, val, opts)
+	if err != nil {
+		return err
+	}
+	if item.Key != read.Key {
+		// the key moved to a new generation
+		return cacher.ErrConflict
+	}
+	read.Value, read.Flags, read.Expiration = item.Value, item.Flags, item.Expiration
+	err = m.client.CompareAndSwap(read)
+	if err == memcache_store.ErrCASConflict || err == memcache_store.ErrNotStored || err == memcache_store.ErrCacheMiss {
+		return cacher.ErrConflict
+	}
+	return err
+}
+
+func stored(err error) (bool, error) {
+	if err == memcache_store.ErrNotStored {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Acquire adds the lock key, lock keys bypass generations so ClearPrefix
+// never drops a held lock. Its fencing tokens are best effort, see fence.
+func (m *Memcache) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error) {
+	err := m.client.Add(&memcache_store.Item{
+		Key:        key,
+		Value:      []byte(owner),
+		Expiration: expiration(ttl),
+	})
+	if err == memcache_store.ErrNotStored {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return m.fence(key + fenceSuffix)
+}
+
+func (m *Memcache) Release(ctx context.Context, key string, owner string) error {
+	// a negative expiration expires the lock at once
+	return m.swapLock(key, owner, -1)
+}
+
+func (m *Memcache) Extend(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	return m.swapLock(key, owner, expiration(ttl))
+}
+
+// swapLock sets the expiration of the lock key if owner still holds it.
+func (m *Memcache) swapLock(key string, owner string, seconds int32) error {
+	item, err := m.client.Get(key)
+	if err == memcache_store.ErrCacheMiss {
+		return cacher.ErrLockNotHeld
+	}
+	if err != nil {
+		return err
+	}
+	if string(item.Value) != owner {
+		return cacher.ErrLockNotHeld
+	}
+	item.Expiration = seconds
+	err = m.client.CompareAndSwap(item)
+	if err == memcache_store.ErrCASConflict || err == memcache_store.ErrNotStored || err == memcache_store.ErrCacheMiss {
+		return cacher.ErrLockNotHeld
+	}
+	return err
+}
+
+// fenceSuffix names the counter issuing the fencing tokens of a lock.
+const fenceSuffix = ":fence"
+
+// fence increments the counter key. It is written without an expiry, but
+// memcache may still evict it under memory pressure, so fencing is best
+// effort: a missing counter restarts from the clock, which keeps tokens
+// growing only while the clocks of the hosts taking the lock agree.
+func (m *Memcache) fence(key string) (int64, error) {
+	n, err := m.incr(key, 1)
+	if err != memcache_store.ErrCacheMiss {
+		return n, err
+	}
+	seed := time.Now().UnixNano()
+	err = m.client.Add(&memcache_store.Item{Key: key, Value: []byte(strconv.FormatInt(seed, 10))})
+	if err == memcache_store.ErrNotStored {
+		return m.incr(key, 1)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return seed, nil
+}
+
+// Memcache cannot report the ttl left on a key.
+func (m *Memcache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return 0, cacher.ErrNotSupported
+}
+
+func (m *Memcache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return m.touch(key, expiration(ttl))
+}
+
+func (m *Memcache) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return m.touch(key, expiration(time.Until(at)))
+}
+
+func (m *Memcache) Persist(ctx context.Context, key string) error {
+	return m.touch(key, 0)
+}
+
+func (m *Memcache) Touch(ctx context.Context, key string) error {
+	return m.touch(key, lifetime(m.ttl))
+}
+
+func (m *Memcache) touch(key string, seconds int32) error {
+	key, err := m.key(key)
+	if err != nil {
+		return err
+	}
+	err = m.client.Touch(key, seconds)
+	if err == memcache_store.ErrCacheMiss {
+		return cacher.ErrKeyNotFound
+	}
+	return err
+}
+
+// maxRelativeExpiration is the longest expiration memcache reads as
+// relative, longer ones are unix timestamps.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+// lifetime is the expiration of an item written with ttl, 0 for a ttl of
+// 0, which never expires.
+func lifetime(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+	return expiration(ttl)
+}
+
+func expiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		// 0 would mean never expire
+		return -1
+	}
+	if ttl > maxRelativeExpiration {
+		return int32(time.Now().Add(ttl).Unix())
+	}
+	if ttl < time.Second {
+		return 1
+	}
+	return int32(ttl.Seconds())
+}
+
 func (m *Memcache) Clear(ctx context.Context) error {
 	// Handler
 	m.client.DeleteAll()
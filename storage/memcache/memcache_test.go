@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	memcache_store "github.com/bradfitz/gomemcache/memcache"
 	"github.com/stretchr/testify/require"
 	"github.com/tinh-tinh/cacher/storage/memcache"
 	"github.com/tinh-tinh/cacher/v2"
@@ -29,6 +30,25 @@ func Test_Expire(t *testing.T) {
 	data, err := cache.Get(ctx, "expire")
 	require.Nil(t, err)
 	require.Empty(t, data)
+
+	// sub-second ttls still expire and ttls past 30 days are not dates
+	err = cache.Set(ctx, "short", []byte("John"), cacher.StoreOptions{Ttl: 500 * time.Millisecond})
+	require.Nil(t, err)
+	err = cache.Set(ctx, "long", []byte("John"), cacher.StoreOptions{Ttl: 60 * 24 * time.Hour})
+	require.Nil(t, err)
+	_, err = cache.(cacher.CounterStore).IncrBy(ctx, "short_counter", 1, cacher.StoreOptions{Ttl: 500 * time.Millisecond})
+	require.Nil(t, err)
+
+	time.Sleep(2 * time.Second)
+	data, err = cache.Get(ctx, "short")
+	require.Nil(t, err)
+	require.Empty(t, data)
+	data, err = cache.Get(ctx, "short_counter")
+	require.Nil(t, err)
+	require.Empty(t, data)
+	data, err = cache.Get(ctx, "long")
+	require.Nil(t, err)
+	require.Equal(t, []byte("John"), data)
 }
 
 func Test_GetSet(t *testing.T) {
@@ -183,3 +203,27 @@ func Test_Module(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, "John", response.Data)
 }
+
+func Test_GenerationEvicted(t *testing.T) {
+	cache := memcache.New(memcache.Options{
+		Addr:        []string{"localhost:11211"},
+		Ttl:         15 * time.Minute,
+		Generations: true,
+	})
+	ctx := context.Background()
+	prefixes := cache.(cacher.PrefixStore)
+
+	err := cache.Set(ctx, "evicted:1", []byte("John"))
+	require.Nil(t, err)
+	err = prefixes.ClearPrefix(ctx, "evicted:")
+	require.Nil(t, err)
+
+	// losing the generation never brings back the values it orphaned
+	client := memcache_store.New("localhost:11211")
+	err = client.Delete("gen:evicted:")
+	require.Nil(t, err)
+
+	data, err := cache.Get(ctx, "evicted:1")
+	require.Nil(t, err)
+	require.Empty(t, data)
+}
@@ -0,0 +1,131 @@
+package memcache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"time"
+
+	memcache_store "github.com/bradfitz/gomemcache/memcache"
+)
+
+const (
+	tagPrefix  = "tag:"
+	flagTagged = 1 << 0
+)
+
+var errTaggedValue = errors.New("memcache: malformed tagged value")
+
+// InvalidateTags bumps the version of every tag. Values remember the tag
+// versions they were written with and read as misses once one changes.
+func (m *Memcache) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		_, err := m.client.Increment(tagPrefix+tag, 1)
+		if err != nil && err != memcache_store.ErrCacheMiss {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagVersions returns the current version of each tag, creating the
+// missing ones.
+func (m *Memcache) tagVersions(tags []string) (map[string]uint64, error) {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagPrefix + tag
+	}
+	items, err := m.client.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]uint64, len(tags))
+	for _, tag := range tags {
+		item, ok := items[tagPrefix+tag]
+		if !ok {
+			item, err = m.seedTag(tag)
+			if err != nil {
+				return nil, err
+			}
+		}
+		version, err := strconv.ParseUint(string(item.Value), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		versions[tag] = version
+	}
+	return versions, nil
+}
+
+func (m *Memcache) seedTag(tag string) (*memcache_store.Item, error) {
+	item := &memcache_store.Item{
+		Key:   tagPrefix + tag,
+		Value: []byte(strconv.FormatInt(time.Now().UnixNano(), 10)),
+	}
+	err := m.client.Add(item)
+	if err == memcache_store.ErrNotStored {
+		return m.client.Get(item.Key)
+	}
+	return item, err
+}
+
+// untagValue unwraps a tagged value, returning nil when one of its tags
+// was invalidated since it was written.
+func (m *Memcache) untagValue(raw []byte) ([]byte, error) {
+	written, val, err := decodeTagged(raw)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(written))
+	for tag := range written {
+		tags = append(tags, tag)
+	}
+	current, err := m.tagVersions(tags)
+	if err != nil {
+		return nil, err
+	}
+	for tag, version := range written {
+		if current[tag] != version {
+			return nil, nil
+		}
+	}
+	return val, nil
+}
+
+func encodeTagged(versions map[string]uint64, val []byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(versions)))
+	for tag, version := range versions {
+		buf = binary.AppendUvarint(buf, uint64(len(tag)))
+		buf = append(buf, tag...)
+		buf = binary.AppendUvarint(buf, version)
+	}
+	return append(buf, val...)
+}
+
+func decodeTagged(raw []byte) (map[string]uint64, []byte, error) {
+	count, n := binary.Uvarint(raw)
+	if n <= 0 {
+		return nil, nil, errTaggedValue
+	}
+	raw = raw[n:]
+
+	versions := make(map[string]uint64, count)
+	for i := uint64(0); i < count; i++ {
+		size, n := binary.Uvarint(raw)
+		if n <= 0 || uint64(len(raw)-n) < size {
+			return nil, nil, errTaggedValue
+		}
+		tag := string(raw[n : n+int(size)])
+		raw = raw[n+int(size):]
+
+		version, n := binary.Uvarint(raw)
+		if n <= 0 {
+			return nil, nil, errTaggedValue
+		}
+		raw = raw[n:]
+		versions[tag] = version
+	}
+	return versions, raw, nil
+}
@@ -0,0 +1,48 @@
+package pebble
+
+import (
+	"context"
+	"strconv"
+
+	pebble_store "github.com/cockroachdb/pebble"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+// IncrBy adds delta to key and writes the sum under s.mu, which every
+// write takes, so no other write lands between the read and the commit.
+func (s *Pebble) IncrBy(ctx context.Context, key string, delta int64, opts ...cacher.StoreOptions) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	val, err := s.live(key)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	n := delta
+	if val == nil {
+		ttl := s.ttl
+		if len(opts) > 0 && opts[0].Ttl > 0 {
+			ttl = opts[0].Ttl
+		}
+		err = s.set(batch, key, []byte(strconv.FormatInt(n, 10)), cacher.StoreOptions{Ttl: ttl})
+	} else {
+		old, err := strconv.ParseInt(string(val), 10, 64)
+		if err != nil {
+			return 0, cacher.ErrNotInteger
+		}
+		n += old
+		// the key keeps its expiry and tags
+		err = batch.Set([]byte(key), []byte(strconv.FormatInt(n, 10)), nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
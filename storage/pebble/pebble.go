@@ -1,8 +1,11 @@
 package pebble
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	pebble_store "github.com/cockroachdb/pebble"
 	"github.com/tinh-tinh/cacher/v2"
@@ -14,6 +17,7 @@ type Options struct {
 	Path    string
 	Sync    bool
 	Connect *pebble_store.Options
+	Ttl     time.Duration
 }
 
 func New(opt Options) cacher.Store {
@@ -23,15 +27,27 @@ func New(opt Options) cacher.Store {
 		return nil
 	}
 
-	return &Pebble{
-		client: client,
-		Sync:   opt.Sync,
+	pebble := &Pebble{
+		client:  client,
+		Sync:    opt.Sync,
+		ttl:     opt.Ttl,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
 	}
+	go pebble.gc(1 * time.Second)
+	return pebble
 }
 
 type Pebble struct {
 	Sync   bool
 	client *pebble_store.DB
+	ttl    time.Duration
+	// mu serializes the writes reading the tag index or a counter.
+	mu sync.Mutex
+	// done stops the gc, which closes stopped once it returns.
+	done    chan struct{}
+	stopped chan struct{}
+	closing sync.Once
 }
 
 func (s *Pebble) Name() string {
@@ -39,41 +55,109 @@ func (s *Pebble) Name() string {
 }
 
 func (s *Pebble) Get(ctx context.Context, key string) ([]byte, error) {
-	keyByte := []byte(key)
-	data, close, err := s.client.Get(keyByte)
+	return s.live(key)
+}
+
+// get returns a copy of the value, which pebble only keeps valid until the
+// closer is closed.
+func (s *Pebble) get(key []byte) ([]byte, error) {
+	data, closer, err := s.client.Get(key)
 	if err != nil {
 		if err == pebble_store.ErrNotFound {
 			return nil, nil
 		}
 		return nil, err
 	}
-
-	if close.Close() != nil {
+	val := append([]byte{}, data...)
+	if err := closer.Close(); err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	return val, nil
 }
 
-// Warning: currently ttl not work in pebble
 func (s *Pebble) Set(ctx context.Context, key string, value []byte, opts ...cacher.StoreOptions) error {
-	keyByte := []byte(key)
-	err := s.client.Set(keyByte, value, &pebble_store.WriteOptions{
-		Sync: s.Sync,
-	})
-	if err != nil {
+	var opt cacher.StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	if err := s.set(batch, key, value, opt); err != nil {
 		return err
 	}
-	return nil
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
 }
 
-func (s *Pebble) Delete(ctx context.Context, key string) error {
-	keyByte := []byte(key)
-	err := s.client.Delete(keyByte, &pebble_store.WriteOptions{Sync: s.Sync})
-	if err != nil {
+func (s *Pebble) set(batch *pebble_store.Batch, key string, value []byte, opt cacher.StoreOptions) error {
+	if err := s.untag(batch, key); err != nil {
 		return err
 	}
-	return nil
+	if err := batch.Set([]byte(key), value, nil); err != nil {
+		return err
+	}
+	ttl := opt.Ttl
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+	if err := s.setExpiry(batch, key, ttl); err != nil {
+		return err
+	}
+	return s.tag(batch, key, opt.Tags)
+}
+
+func (s *Pebble) Delete(ctx context.Context, key string) error {
+	return s.MDelete(ctx, key)
+}
+
+func (s *Pebble) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := s.live(key)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+func (s *Pebble) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	for _, param := range params {
+		if err := s.set(batch, param.Key, param.Value, param.Options); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
+}
+
+func (s *Pebble) MDelete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	for _, key := range keys {
+		if err := s.untag(batch, key); err != nil {
+			return err
+		}
+		if err := batch.Delete([]byte(key), nil); err != nil {
+			return err
+		}
+		if err := batch.Delete(expiryKey(key), nil); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
 }
 
 func (s *Pebble) Clear(ctx context.Context) error {
@@ -87,6 +171,100 @@ func (s *Pebble) Clear(ctx context.Context) error {
 	return nil
 }
 
+func (s *Pebble) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := s.scan(ctx, prefix, func(key []byte) {
+		keys = append(keys, string(key))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *Pebble) Count(ctx context.Context, prefix string) (int, error) {
+	count := 0
+	err := s.scan(ctx, prefix, func(key []byte) {
+		count++
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *Pebble) ClearPrefix(ctx context.Context, prefix string) error {
+	if prefix == "" {
+		return s.Clear(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	if err := s.untagPrefix(ctx, batch, prefix); err != nil {
+		return err
+	}
+	for _, lower := range [][]byte{[]byte(prefix), expiryKey(prefix)} {
+		if err := batch.DeleteRange(lower, upperBound(lower), nil); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
+}
+
+func (s *Pebble) scan(ctx context.Context, prefix string, fnc func(key []byte)) error {
+	opts := &pebble_store.IterOptions{LowerBound: []byte(prefix)}
+	if prefix != "" {
+		opts.UpperBound = upperBound([]byte(prefix))
+	}
+	iter, err := s.client.NewIterWithContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if bytes.HasPrefix(iter.Key(), internalPrefix) {
+			continue
+		}
+		expired, err := s.expired(string(iter.Key()))
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		if !expired {
+			fnc(iter.Key())
+		}
+	}
+	return iter.Close()
+}
+
+// upperBound returns the smallest key greater than every key with prefix.
+func upperBound(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// GetClient returns the database. Close the store rather than the client,
+// pebble panics when the gc reads a closed database.
 func (r *Pebble) GetClient() *pebble_store.DB {
 	return r.client
 }
+
+// Close stops the background gc and closes the database.
+func (r *Pebble) Close() error {
+	err := pebble_store.ErrClosed
+	r.closing.Do(func() {
+		close(r.done)
+		<-r.stopped
+		err = r.client.Close()
+	})
+	return err
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"io"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	pebble_store "github.com/cockroachdb/pebble"
 	"github.com/stretchr/testify/require"
@@ -181,3 +183,138 @@ func Test_GetClient(t *testing.T) {
 	require.True(t, ok)
 	require.NotNil(t, cacheRedis.GetClient())
 }
+
+func Test_Tags(t *testing.T) {
+	cache := pebble.New(pebble.Options{
+		Path:    t.TempDir(),
+		Connect: &pebble_store.Options{},
+	})
+	ctx := context.Background()
+
+	err := cache.Set(ctx, "pages:1", []byte("a"), cacher.StoreOptions{Tags: []string{"product:42"}})
+	require.Nil(t, err)
+	err = cache.Set(ctx, "lists:1", []byte("b"), cacher.StoreOptions{Tags: []string{"product:42", "product:7"}})
+	require.Nil(t, err)
+	err = cache.Set(ctx, "pages:2", []byte("c"), cacher.StoreOptions{Tags: []string{"product:7"}})
+	require.Nil(t, err)
+
+	keys, err := cache.(cacher.PrefixStore).Keys(ctx, "")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"pages:1", "pages:2", "lists:1"}, keys)
+
+	err = cache.(cacher.TagStore).InvalidateTags(ctx, "product:42")
+	require.Nil(t, err)
+
+	data, err := cache.Get(ctx, "pages:1")
+	require.Nil(t, err)
+	require.Nil(t, data)
+
+	data, err = cache.Get(ctx, "lists:1")
+	require.Nil(t, err)
+	require.Nil(t, data)
+
+	data, err = cache.Get(ctx, "pages:2")
+	require.Nil(t, err)
+	require.Equal(t, []byte("c"), data)
+
+	// clearing a prefix drops its tags, so a rewrite is not invalidated
+	err = cache.(cacher.PrefixStore).ClearPrefix(ctx, "pages:")
+	require.Nil(t, err)
+	err = cache.Set(ctx, "pages:2", []byte("d"))
+	require.Nil(t, err)
+	err = cache.(cacher.TagStore).InvalidateTags(ctx, "product:7")
+	require.Nil(t, err)
+
+	data, err = cache.Get(ctx, "pages:2")
+	require.Nil(t, err)
+	require.Equal(t, []byte("d"), data)
+}
+
+func Test_Ttl(t *testing.T) {
+	cache := pebble.New(pebble.Options{
+		Path:    t.TempDir(),
+		Connect: &pebble_store.Options{},
+		Ttl:     15 * time.Minute,
+	})
+	store := cache.(cacher.TtlStore)
+	ctx := context.Background()
+
+	err := cache.Set(ctx, "short", []byte("a"), cacher.StoreOptions{Ttl: 100 * time.Millisecond})
+	require.Nil(t, err)
+	err = cache.Set(ctx, "long", []byte("b"))
+	require.Nil(t, err)
+
+	ttl, err := store.TTL(ctx, "long")
+	require.Nil(t, err)
+	require.Greater(t, ttl, 14*time.Minute)
+
+	err = store.Persist(ctx, "long")
+	require.Nil(t, err)
+	ttl, err = store.TTL(ctx, "long")
+	require.Nil(t, err)
+	require.Equal(t, cacher.NoTtl, ttl)
+
+	time.Sleep(150 * time.Millisecond)
+	data, err := cache.Get(ctx, "short")
+	require.Nil(t, err)
+	require.Nil(t, data)
+
+	_, err = store.TTL(ctx, "short")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	err = store.Expire(ctx, "short", time.Minute)
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+func Test_IncrBy(t *testing.T) {
+	cache := pebble.New(pebble.Options{
+		Path: t.TempDir(),
+		Ttl:  15 * time.Minute,
+	})
+	store := cache.(cacher.CounterStore)
+	ctx := context.Background()
+
+	n, err := store.IncrBy(ctx, "views", 5)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), n)
+
+	n, err = store.IncrBy(ctx, "views", -2)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), n)
+
+	err = cache.Set(ctx, "name", []byte("John"))
+	require.Nil(t, err)
+	_, err = store.IncrBy(ctx, "name", 1)
+	require.ErrorIs(t, err, cacher.ErrNotInteger)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.IncrBy(ctx, "hits", 1)
+			require.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	data, err := cache.Get(ctx, "hits")
+	require.Nil(t, err)
+	require.Equal(t, []byte("50"), data)
+}
+
+func Test_Close(t *testing.T) {
+	cache := pebble.New(pebble.Options{
+		Path:    t.TempDir(),
+		Connect: &pebble_store.Options{},
+		Ttl:     time.Millisecond,
+	})
+	ctx := context.Background()
+	err := cache.Set(ctx, "1", []byte("John"))
+	require.Nil(t, err)
+
+	store := cache.(*pebble.Pebble)
+	require.Nil(t, store.Close())
+	require.ErrorIs(t, store.Close(), pebble_store.ErrClosed)
+
+	// the gc no longer reads the closed database
+	time.Sleep(1500 * time.Millisecond)
+}
@@ -0,0 +1,125 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	pebble_store "github.com/cockroachdb/pebble"
+)
+
+// internalPrefix marks the tag index keys, which Keys and Count skip.
+var internalPrefix = []byte("\x00cacher\x00")
+
+// tagIndexKey maps a tag to one of its keys.
+func tagIndexKey(tag string, key string) []byte {
+	return append(tagIndexPrefix(tag), key...)
+}
+
+func tagIndexPrefix(tag string) []byte {
+	b := append([]byte{}, internalPrefix...)
+	b = append(b, "tag\x00"...)
+	b = append(b, tag...)
+	return append(b, 0)
+}
+
+// keyTagsKey maps a key to the tags it was written with.
+func keyTagsKey(key string) []byte {
+	b := append([]byte{}, internalPrefix...)
+	b = append(b, "tags\x00"...)
+	return append(b, key...)
+}
+
+func (s *Pebble) tag(batch *pebble_store.Batch, key string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	for _, tag := range tags {
+		if err := batch.Set(tagIndexKey(tag, key), nil, nil); err != nil {
+			return err
+		}
+	}
+	return batch.Set(keyTagsKey(key), []byte(strings.Join(tags, "\x00")), nil)
+}
+
+// untag removes the index entries of the tags key was last written with.
+// Callers hold s.mu until the batch is committed.
+func (s *Pebble) untag(batch *pebble_store.Batch, key string) error {
+	raw, err := s.get(keyTagsKey(key))
+	if err != nil || raw == nil {
+		return err
+	}
+	for _, tag := range bytes.Split(raw, []byte{0}) {
+		if err := batch.Delete(tagIndexKey(string(tag), key), nil); err != nil {
+			return err
+		}
+	}
+	return batch.Delete(keyTagsKey(key), nil)
+}
+
+// untagPrefix removes the index entries of the keys starting with prefix.
+func (s *Pebble) untagPrefix(ctx context.Context, batch *pebble_store.Batch, prefix string) error {
+	lower := keyTagsKey(prefix)
+	iter, err := s.client.NewIterWithContext(ctx, &pebble_store.IterOptions{
+		LowerBound: lower,
+		UpperBound: upperBound(lower),
+	})
+	if err != nil {
+		return err
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := string(iter.Key()[len(lower)-len(prefix):])
+		for _, tag := range bytes.Split(iter.Value(), []byte{0}) {
+			if err := batch.Delete(tagIndexKey(string(tag), key), nil); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	return batch.DeleteRange(lower, upperBound(lower), nil)
+}
+
+func (s *Pebble) InvalidateTags(ctx context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.client.NewBatch()
+	defer batch.Close()
+
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		prefix := tagIndexPrefix(tag)
+		iter, err := s.client.NewIterWithContext(ctx, &pebble_store.IterOptions{
+			LowerBound: prefix,
+			UpperBound: upperBound(prefix),
+		})
+		if err != nil {
+			return err
+		}
+		for iter.First(); iter.Valid(); iter.Next() {
+			key := string(iter.Key()[len(prefix):])
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := s.untag(batch, key); err != nil {
+				iter.Close()
+				return err
+			}
+			if err := batch.Delete([]byte(key), nil); err != nil {
+				iter.Close()
+				return err
+			}
+			if err := batch.Delete(expiryKey(key), nil); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
+}
@@ -0,0 +1,170 @@
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	pebble_store "github.com/cockroachdb/pebble"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+// expiryKey maps a key to its expiry in unix nanoseconds. Keys without one
+// never expire.
+func expiryKey(key string) []byte {
+	b := append([]byte{}, internalPrefix...)
+	b = append(b, "exp\x00"...)
+	return append(b, key...)
+}
+
+// expiry returns when key expires, the zero time when it never does.
+func (s *Pebble) expiry(key string) (time.Time, error) {
+	raw, err := s.get(expiryKey(key))
+	if err != nil || len(raw) != 8 {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(raw))), nil
+}
+
+func (s *Pebble) setExpiry(batch *pebble_store.Batch, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return batch.Delete(expiryKey(key), nil)
+	}
+	return s.setExpiryAt(batch, key, time.Now().Add(ttl))
+}
+
+func (s *Pebble) setExpiryAt(batch *pebble_store.Batch, key string, at time.Time) error {
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, uint64(at.UnixNano()))
+	return batch.Set(expiryKey(key), raw, nil)
+}
+
+// live returns the value of key, nil when it is missing or expired.
+func (s *Pebble) live(key string) ([]byte, error) {
+	val, err := s.get([]byte(key))
+	if err != nil || val == nil {
+		return nil, err
+	}
+	expired, err := s.expired(key)
+	if err != nil || expired {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (s *Pebble) expired(key string) (bool, error) {
+	at, err := s.expiry(key)
+	if err != nil {
+		return false, err
+	}
+	return !at.IsZero() && !time.Now().Before(at), nil
+}
+
+func (s *Pebble) TTL(ctx context.Context, key string) (time.Duration, error) {
+	val, err := s.live(key)
+	if err != nil {
+		return 0, err
+	}
+	if val == nil {
+		return 0, cacher.ErrKeyNotFound
+	}
+	at, err := s.expiry(key)
+	if err != nil {
+		return 0, err
+	}
+	if at.IsZero() {
+		return cacher.NoTtl, nil
+	}
+	return time.Until(at), nil
+}
+
+func (s *Pebble) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	val, err := s.live(key)
+	if err != nil || val == nil {
+		return val, 0, err
+	}
+	at, err := s.expiry(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if at.IsZero() {
+		return val, cacher.NoTtl, nil
+	}
+	return val, time.Until(at), nil
+}
+
+func (s *Pebble) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.ExpireAt(ctx, key, time.Now().Add(ttl))
+}
+
+func (s *Pebble) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return s.updateExpiry(key, func(batch *pebble_store.Batch) error {
+		return s.setExpiryAt(batch, key, at)
+	})
+}
+
+func (s *Pebble) Persist(ctx context.Context, key string) error {
+	return s.updateExpiry(key, func(batch *pebble_store.Batch) error {
+		return batch.Delete(expiryKey(key), nil)
+	})
+}
+
+func (s *Pebble) Touch(ctx context.Context, key string) error {
+	return s.updateExpiry(key, func(batch *pebble_store.Batch) error {
+		return s.setExpiry(batch, key, s.ttl)
+	})
+}
+
+func (s *Pebble) updateExpiry(key string, fnc func(batch *pebble_store.Batch) error) error {
+	val, err := s.live(key)
+	if err != nil {
+		return err
+	}
+	if val == nil {
+		return cacher.ErrKeyNotFound
+	}
+
+	batch := s.client.NewBatch()
+	defer batch.Close()
+	if err := fnc(batch); err != nil {
+		return err
+	}
+	return batch.Commit(&pebble_store.WriteOptions{Sync: s.Sync})
+}
+
+// gc deletes expired keys in the background until the store is closed.
+func (s *Pebble) gc(sleep time.Duration) {
+	ticker := time.NewTicker(sleep)
+	defer ticker.Stop()
+	defer close(s.stopped)
+
+	prefix := expiryKey("")
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		iter, err := s.client.NewIter(&pebble_store.IterOptions{
+			LowerBound: prefix,
+			UpperBound: upperBound(prefix),
+		})
+		if err != nil {
+			continue
+		}
+		now := uint64(time.Now().UnixNano())
+		var expired []string
+		for iter.First(); iter.Valid(); iter.Next() {
+			if len(iter.Value()) == 8 && binary.BigEndian.Uint64(iter.Value()) <= now {
+				expired = append(expired, string(iter.Key()[len(prefix):]))
+			}
+		}
+		iter.Close()
+		for _, key := range expired {
+			// skip keys rewritten since the scan
+			if ok, err := s.expired(key); err == nil && ok {
+				s.Delete(context.Background(), key)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	redis_store "github.com/redis/go-redis/v9"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+// DefaultChannel is the pub/sub channel buses use when none is given.
+const DefaultChannel = "cacher:invalidations"
+
+// Bus is an InvalidationBus over redis pub/sub. Each Bus has an origin of
+// its own, so give every instance its own Bus.
+type Bus struct {
+	client  *redis_store.Client
+	channel string
+	origin  string
+}
+
+// NewBus returns a bus on channel, DefaultChannel when empty, with a
+// random origin.
+func NewBus(client *redis_store.Client, channel string) (cacher.InvalidationBus, error) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return &Bus{
+		client:  client,
+		channel: channel,
+		origin:  hex.EncodeToString(buf),
+	}, nil
+}
+
+func (b *Bus) Origin() string {
+	return b.origin
+}
+
+func (b *Bus) Publish(ctx context.Context, event cacher.Invalidation) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// Subscribe delivers events from other origins until cancel is called.
+// Events published while the subscription reconnects are lost.
+func (b *Bus) Subscribe(handler func(event cacher.Invalidation)) (func(), error) {
+	ctx := context.Background()
+	sub := b.client.Subscribe(ctx, b.channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range sub.Channel() {
+			var event cacher.Invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			if event.Origin != b.origin {
+				handler(event)
+			}
+		}
+	}()
+	return func() { sub.Close() }, nil
+}
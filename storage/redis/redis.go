@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/tinh-tinh/cacher/v2"
@@ -50,30 +52,482 @@ func (r *Redis) Set(ctx context.Context, key string, val []byte, opts ...cacher.
 	} else {
 		ttl = r.ttl
 	}
-	err := r.client.Set(ctx, key, val, ttl).Err()
+	var tags []string
+	if len(opts) > 0 {
+		tags = opts[0].Tags
+	}
+	return setTaggedScript.Run(ctx, r.client, taggedKeys(key, tags), val, ttl.Milliseconds()).Err()
+}
+
+func (r *Redis) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return [][]byte{}, nil
+	}
+	res, err := r.client.MGet(ctx, keys...).Result()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	vals := make([][]byte, len(keys))
+	for i, v := range res {
+		if s, ok := v.(string); ok {
+			vals[i] = []byte(s)
+		}
+	}
+	return vals, nil
+}
+
+func (r *Redis) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis_store.Pipeliner) error {
+		for _, param := range params {
+			ttl := param.Options.Ttl
+			if ttl <= 0 {
+				ttl = r.ttl
+			}
+			setTaggedScript.Eval(ctx, pipe, taggedKeys(param.Key, param.Options.Tags), param.Value, ttl.Milliseconds())
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *Redis) MDelete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	indexed := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		indexed = append(indexed, key, keyTagsPrefix+key)
+	}
+	return deleteScript.Run(ctx, r.client, indexed).Err()
 }
 
 func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.MDelete(ctx, key)
+}
+
+func (r *Redis) Clear(ctx context.Context) error {
 	// Handler
-	err := r.client.Del(ctx, key).Err()
+	r.client.FlushDB(ctx).Err()
+	return nil
+}
+
+func (r *Redis) IncrBy(ctx context.Context, key string, delta int64, opts ...cacher.StoreOptions) (int64, error) {
+	ttl := r.ttl
+	if len(opts) > 0 && opts[0].Ttl > 0 {
+		ttl = opts[0].Ttl
+	}
+	n, err := incrByScript.Run(ctx, r.client, []string{key}, delta, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// incrByScript increments KEYS[1] and sets its ttl only when it creates it.
+var incrByScript = redis_store.NewScript(`
+local created = redis.call('EXISTS', KEYS[1]) == 0
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if created and tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n
+`)
+
+func (r *Redis) SetNX(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	return r.setIf(ctx, key, val, opts, "nx")
+}
+
+func (r *Redis) Replace(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	return r.setIf(ctx, key, val, opts, "xx")
+}
+
+// GetVersion returns the value itself as its version, CompareAndSwap
+// succeeds while the key still holds the same bytes.
+func (r *Redis) GetVersion(ctx context.Context, key string) ([]byte, cacher.Version, error) {
+	val, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis_store.Nil {
+			return nil, nil, cacher.ErrKeyNotFound
+		}
+		return nil, nil, err
+	}
+	return val, string(val), nil
+}
+
+func (r *Redis) CompareAndSwap(ctx context.Context, key string, val []byte, version cacher.Version, opts ...cacher.StoreOptions) error {
+	expected, ok := version.(string)
+	if !ok {
+		return cacher.ErrConflict
+	}
+	swapped, err := r.setIf(ctx, key, val, opts, "cas", expected)
 	if err != nil {
 		return err
 	}
+	if !swapped {
+		return cacher.ErrConflict
+	}
+	return nil
+}
+
+func (r *Redis) setIf(ctx context.Context, key string, val []byte, opts []cacher.StoreOptions, cond ...any) (bool, error) {
+	ttl := r.ttl
+	var tags []string
+	if len(opts) > 0 {
+		if opts[0].Ttl > 0 {
+			ttl = opts[0].Ttl
+		}
+		tags = opts[0].Tags
+	}
+	args := append([]any{val, ttl.Milliseconds()}, cond...)
+	n, err := setTaggedScript.Run(ctx, r.client, taggedKeys(key, tags), args...).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *Redis) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error) {
+	return acquireScript.Run(ctx, r.client, []string{key, key + fenceSuffix}, owner, ttl.Milliseconds()).Int64()
+}
+
+func (r *Redis) Release(ctx context.Context, key string, owner string) error {
+	return r.held(releaseScript.Run(ctx, r.client, []string{key}, owner).Int())
+}
+
+func (r *Redis) Extend(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	return r.held(extendScript.Run(ctx, r.client, []string{key}, owner, ttl.Milliseconds()).Int())
+}
 
+func (r *Redis) held(n int, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return cacher.ErrLockNotHeld
+	}
 	return nil
 }
 
-func (r *Redis) Clear(ctx context.Context) error {
-	// Handler
-	r.client.FlushDB(ctx).Err()
+// fenceSuffix names the counter issuing the fencing tokens of a lock.
+const fenceSuffix = ":fence"
+
+// acquireScript takes the lock KEYS[1] for ARGV[1] and returns the next
+// token from KEYS[2], or 0 while the lock is held.
+var acquireScript = redis_store.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock KEYS[1] if ARGV[1] still owns it.
+var releaseScript = redis_store.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// extendScript resets the ttl of the lock KEYS[1] if ARGV[1] still owns it.
+var extendScript = redis_store.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+func (r *Redis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// go-redis reports -1 and -2 unscaled
+	switch ttl {
+	case -2:
+		return 0, cacher.ErrKeyNotFound
+	case -1:
+		return cacher.NoTtl, nil
+	}
+	return ttl, nil
+}
+
+// GetWithTTL reads key and its ttl in one pipeline.
+func (r *Redis) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	var get *redis_store.StringCmd
+	var pttl *redis_store.DurationCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis_store.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err == redis_store.Nil {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	val, err := get.Bytes()
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := pttl.Val()
+	switch ttl {
+	case -2:
+		// expired after the read
+		ttl = 0
+	case -1:
+		ttl = cacher.NoTtl
+	}
+	return val, ttl, nil
+}
+
+func (r *Redis) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return found(r.client.PExpire(ctx, key, ttl).Result())
+}
+
+func (r *Redis) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return found(r.client.PExpireAt(ctx, key, at).Result())
+}
+
+func (r *Redis) Persist(ctx context.Context, key string) error {
+	exists, err := r.client.Exists(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return cacher.ErrKeyNotFound
+	}
+	return r.client.Persist(ctx, key).Err()
+}
+
+func (r *Redis) Touch(ctx context.Context, key string) error {
+	if r.ttl <= 0 {
+		return r.Persist(ctx, key)
+	}
+	return r.Expire(ctx, key, r.ttl)
+}
+
+func found(ok bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cacher.ErrKeyNotFound
+	}
 	return nil
 }
 
+func (r *Redis) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := r.scan(ctx, prefix, func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *Redis) Count(ctx context.Context, prefix string) (int, error) {
+	count := 0
+	err := r.scan(ctx, prefix, func(batch []string) error {
+		count += len(batch)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Redis) ClearPrefix(ctx context.Context, prefix string) error {
+	return r.scan(ctx, prefix, func(batch []string) error {
+		return r.unlink(ctx, batch, nil)
+	})
+}
+
+func (r *Redis) scan(ctx context.Context, prefix string, fnc func(batch []string) error) error {
+	match := globEscaper.Replace(prefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fnc(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+const scanCount = 500
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func (r *Redis) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	sets := tagKeys(tags)
+	members, err := r.client.SUnion(ctx, sets...).Result()
+	if err != nil {
+		return err
+	}
+	for len(members) > scanCount {
+		if err := r.unlink(ctx, members[:scanCount], nil); err != nil {
+			return err
+		}
+		members = members[scanCount:]
+	}
+	return r.unlink(ctx, members, sets)
+}
+
+// unlink unlinks keys along with their tag indexes, takes them out of the
+// tag sets their indexes list and unlinks the tag sets in drop.
+func (r *Redis) unlink(ctx context.Context, keys []string, drop []string) error {
+	indexes := make([]*redis_store.StringSliceCmd, len(keys))
+	if len(keys) > 0 {
+		_, err := r.client.Pipelined(ctx, func(pipe redis_store.Pipeliner) error {
+			for i, key := range keys {
+				indexes[i] = pipe.SMembers(ctx, keyTagsPrefix+key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var sets []string
+	for _, index := range indexes {
+		for _, set := range index.Val() {
+			if !slices.Contains(drop, set) && !slices.Contains(sets, set) {
+				sets = append(sets, set)
+			}
+		}
+	}
+	scriptKeys := slices.Concat(drop, sets)
+	for _, key := range keys {
+		scriptKeys = append(scriptKeys, key, keyTagsPrefix+key)
+	}
+	if len(scriptKeys) == 0 {
+		return nil
+	}
+	return unlinkScript.Run(ctx, r.client, scriptKeys, len(drop), len(sets)).Err()
+}
+
+const (
+	tagPrefix = "tag:"
+	// keyTagsPrefix names the set of tag sets a key belongs to, so writes
+	// and deletes can take the key out of them.
+	keyTagsPrefix = "tags:"
+)
+
+func tagKeys(tags []string) []string {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagPrefix + tag
+	}
+	return keys
+}
+
+// taggedKeys returns the script keys of a write: the key, its tag index
+// and its tag sets.
+func taggedKeys(key string, tags []string) []string {
+	return append([]string{key, keyTagsPrefix + key}, tagKeys(tags)...)
+}
+
+// setTaggedScript writes KEYS[1], takes it out of the tag sets listed in
+// its index KEYS[2] and adds it to the tag sets in KEYS[3:]. A tag set
+// lives at least as long as its longest lived member, and an index as long
+// as its longest lived tag set. The optional ARGV[3] makes the write
+// conditional: "nx" when the key is missing, "xx" when it exists, "cas"
+// when it still holds ARGV[4].
+var setTaggedScript = redis_store.NewScript(`
+local mode = ARGV[3]
+if mode then
+	local cur = redis.call('GET', KEYS[1])
+	if (mode == 'nx' and cur) or (mode == 'xx' and not cur) or (mode == 'cas' and cur ~= ARGV[4]) then
+		return 0
+	end
+end
+for _, tag in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	redis.call('SREM', tag, KEYS[1])
+end
+redis.call('DEL', KEYS[2])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+local longest = 0
+for i = 3, #KEYS do
+	redis.call('SADD', KEYS[i], KEYS[1])
+	redis.call('SADD', KEYS[2], KEYS[i])
+	local left = redis.call('PTTL', KEYS[i])
+	if ttl == 0 then
+		redis.call('PERSIST', KEYS[i])
+	elseif (left == -1 and redis.call('SCARD', KEYS[i]) == 1) or (left >= 0 and left < ttl) then
+		redis.call('PEXPIRE', KEYS[i], ttl)
+	end
+	left = redis.call('PTTL', KEYS[i])
+	if longest >= 0 and (left == -1 or left > longest) then
+		longest = left
+	end
+end
+if longest > 0 then
+	redis.call('PEXPIRE', KEYS[2], longest)
+end
+return 1
+`)
+
+// deleteScript deletes the keys in the odd KEYS and takes each out of the
+// tag sets listed in the index following it.
+var deleteScript = redis_store.NewScript(`
+for i = 1, #KEYS, 2 do
+	for _, tag in ipairs(redis.call('SMEMBERS', KEYS[i + 1])) do
+		redis.call('SREM', tag, KEYS[i])
+	end
+	redis.call('DEL', KEYS[i], KEYS[i + 1])
+end
+return 1
+`)
+
+// unlinkScript unlinks the ARGV[1] tag sets first in KEYS, takes the keys
+// in the odd KEYS after the ARGV[2] tag sets following them out of those
+// tag sets, and unlinks these keys along with the index following each.
+var unlinkScript = redis_store.NewScript(`
+local drop = tonumber(ARGV[1])
+local sets = tonumber(ARGV[2])
+local members, keys = {}, {}
+for i = drop + sets + 1, #KEYS, 2 do
+	table.insert(members, KEYS[i])
+	table.insert(keys, KEYS[i])
+	table.insert(keys, KEYS[i + 1])
+end
+for i = drop + 1, drop + sets do
+	for j = 1, #members, 500 do
+		redis.call('SREM', KEYS[i], unpack(members, j, math.min(j + 499, #members)))
+	end
+end
+for j = 1, #keys, 500 do
+	redis.call('UNLINK', unpack(keys, j, math.min(j + 499, #keys)))
+end
+for i = 1, drop do
+	redis.call('UNLINK', KEYS[i])
+end
+return 1
+`)
+
 func (r *Redis) GetClient() *redis_store.Client {
 	return r.client
 }
@@ -134,6 +134,58 @@ func Test_Delete(t *testing.T) {
 	require.Empty(t, data)
 }
 
+func Test_Untag(t *testing.T) {
+	cache := redis.New(redis.Options{
+		Connect: &redis_store.Options{
+			Addr:     "localhost:6379",
+			DB:       0,
+			Password: "",
+		},
+		Ttl: 15 * time.Minute,
+	})
+	tags := cache.(cacher.TagStore)
+	ctx := context.Background()
+
+	// a rewrite without tags leaves the tag sets
+	err := cache.Set(ctx, "page:1", []byte("v1"), cacher.StoreOptions{Tags: []string{"product:1"}})
+	require.Nil(t, err)
+	err = cache.Set(ctx, "page:1", []byte("v2"))
+	require.Nil(t, err)
+	err = tags.InvalidateTags(ctx, "product:1")
+	require.Nil(t, err)
+
+	data, err := cache.Get(ctx, "page:1")
+	require.Nil(t, err)
+	require.Equal(t, []byte("v2"), data)
+
+	// so does a delete
+	err = cache.Set(ctx, "page:2", []byte("v1"), cacher.StoreOptions{Tags: []string{"product:2"}})
+	require.Nil(t, err)
+	err = cache.Delete(ctx, "page:2")
+	require.Nil(t, err)
+	err = cache.Set(ctx, "page:2", []byte("v2"))
+	require.Nil(t, err)
+	err = tags.InvalidateTags(ctx, "product:2")
+	require.Nil(t, err)
+
+	data, err = cache.Get(ctx, "page:2")
+	require.Nil(t, err)
+	require.Equal(t, []byte("v2"), data)
+
+	// and clearing a prefix
+	err = cache.Set(ctx, "page:3", []byte("v1"), cacher.StoreOptions{Tags: []string{"product:3"}})
+	require.Nil(t, err)
+	err = cache.(cacher.PrefixStore).ClearPrefix(ctx, "page:3")
+	require.Nil(t, err)
+	client := cache.(*redis.Redis).GetClient()
+	members, err := client.SMembers(ctx, "tag:product:3").Result()
+	require.Nil(t, err)
+	require.Empty(t, members)
+	exists, err := client.Exists(ctx, "tags:page:3").Result()
+	require.Nil(t, err)
+	require.Zero(t, exists)
+}
+
 func Test_Module(t *testing.T) {
 	userController := func(module core.Module) core.Controller {
 		cache := cacher.InjectSchema[[]byte](module)
@@ -230,3 +282,33 @@ func Test_GetClient(t *testing.T) {
 	require.True(t, ok)
 	require.NotNil(t, cacheRedis.GetClient())
 }
+
+func Test_Bus(t *testing.T) {
+	client := redis_store.NewClient(&redis_store.Options{
+		Addr: "localhost:6379",
+	})
+	pod1, err := redis.NewBus(client, "")
+	require.Nil(t, err)
+	pod2, err := redis.NewBus(client, "")
+	require.Nil(t, err)
+
+	local := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute})
+	ctx := context.Background()
+	require.Nil(t, local.Set(ctx, "users:1", []byte("John")))
+
+	cancel, err := cacher.SubscribeStore(pod2, local)
+	require.Nil(t, err)
+	defer cancel()
+
+	schema := cacher.NewSchema[string](cacher.Config{
+		Store:     cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Minute}),
+		Namespace: "users",
+		Bus:       pod1,
+	})
+	require.Nil(t, schema.Delete("1"))
+
+	require.Eventually(t, func() bool {
+		_, err := local.Get(ctx, "users:1")
+		return err == cacher.ErrKeyNotFound
+	}, time.Second, 10*time.Millisecond)
+}
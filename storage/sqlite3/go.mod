@@ -16,4 +16,4 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
-replace github.com/tinh-tinh/cacher => ../../
+replace github.com/tinh-tinh/cacher/v2 => ../../
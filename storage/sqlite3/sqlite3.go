@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -25,10 +26,70 @@ const CreateTable = `
 CREATE TABLE IF NOT EXISTS cache (
     key TEXT PRIMARY KEY,
     value TEXT,
+    expires_at DATETIME NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS cache_tags (
+    tag TEXT NOT NULL,
+    key TEXT NOT NULL,
+    PRIMARY KEY (tag, key)
+);
+CREATE INDEX IF NOT EXISTS cache_tags_key ON cache_tags (key);
+CREATE TABLE IF NOT EXISTS cache_locks (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    token INTEGER NOT NULL,
     expires_at DATETIME NOT NULL
 );
 `
 
+// AddVersion adds the version column to caches created before it existed.
+const AddVersion = `ALTER TABLE cache ADD COLUMN version INTEGER NOT NULL DEFAULT 0`
+
+// SetQuery upserts a row. Conditional writes narrow the update with a
+// WHERE clause and check whether a row was written. Every write of a row
+// bumps its version, which CompareAndSwap checks.
+const SetQuery = `
+INSERT INTO cache (key, value, expires_at) VALUES (?1, ?2, ?3)
+ON CONFLICT (key) DO UPDATE SET
+    value = excluded.value,
+    expires_at = excluded.expires_at,
+    version = version + 1
+`
+
+// ReplaceQuery overwrites a live row.
+const ReplaceQuery = `
+UPDATE cache SET value = ?2, expires_at = ?3, version = version + 1
+WHERE key = ?1 AND expires_at > DATETIME('now')
+`
+
+// AcquireQuery takes a free or expired lock and returns its next fencing
+// token. Released locks keep their row so tokens keep growing.
+const AcquireQuery = `
+INSERT INTO cache_locks (name, owner, token, expires_at) VALUES (?1, ?2, 1, ?3)
+ON CONFLICT (name) DO UPDATE SET
+    owner = excluded.owner,
+    token = token + 1,
+    expires_at = excluded.expires_at
+WHERE expires_at <= DATETIME('now')
+RETURNING token;
+`
+
+// IncrByQuery upserts a counter, restarting it along with its expiry when
+// the stored row has already expired.
+const IncrByQuery = `
+INSERT INTO cache (key, value, expires_at) VALUES (?1, CAST(?2 AS TEXT), ?3)
+ON CONFLICT (key) DO UPDATE SET
+    value = CASE WHEN expires_at > DATETIME('now')
+        THEN CAST(CAST(value AS INTEGER) + ?2 AS TEXT)
+        ELSE CAST(?2 AS TEXT) END,
+    expires_at = CASE WHEN expires_at > DATETIME('now')
+        THEN expires_at
+        ELSE excluded.expires_at END,
+    version = version + 1
+RETURNING CAST(value AS INTEGER);
+`
+
 func New(opt Options) cacher.Store {
 	db, err := sql.Open("sqlite3", opt.Addr)
 	if err != nil {
@@ -39,6 +100,10 @@ func New(opt Options) cacher.Store {
 		fmt.Println(err)
 		return nil
 	}
+	if _, err := db.Exec(AddVersion); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+		fmt.Println(err)
+		return nil
+	}
 	sqlite := &Sqlite{
 		db:  db,
 		ttl: opt.Ttl,
@@ -58,20 +123,199 @@ func (s *Sqlite) Name() string {
 }
 
 func (s *Sqlite) Set(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) error {
-	var ttl time.Duration
+	var opt cacher.StoreOptions
 	if len(opts) > 0 {
-		ttl = opts[0].Ttl
-	} else {
-		ttl = s.ttl
+		opt = opts[0]
 	}
-	_, err := s.db.ExecContext(ctx, "INSERT INTO cache (key, value, expires_at) VALUES (?, ?, ?) ", key, string(val), ParseTimestap(ttl))
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := s.set(ctx, tx, key, val, opt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+func (s *Sqlite) set(ctx context.Context, tx *sql.Tx, key string, val []byte, opt cacher.StoreOptions) error {
+	_, err := s.put(ctx, tx, SetQuery, key, val, opt)
+	return err
+}
+
+// put runs query with the row of key, plus args, and retags the key when
+// the query wrote it.
+func (s *Sqlite) put(ctx context.Context, tx *sql.Tx, query string, key string, val []byte, opt cacher.StoreOptions, args ...any) (bool, error) {
+	ttl := opt.Ttl
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+
+	args = append([]any{key, string(val), ParseTimestap(ttl)}, args...)
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM cache_tags WHERE key = ?", key)
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range opt.Tags {
+		_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO cache_tags (tag, key) VALUES (?, ?)", tag, key)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s *Sqlite) SetNX(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	return s.setIf(ctx, SetQuery+"WHERE expires_at <= DATETIME('now')", key, val, opts)
+}
+
+func (s *Sqlite) Replace(ctx context.Context, key string, val []byte, opts ...cacher.StoreOptions) (bool, error) {
+	return s.setIf(ctx, ReplaceQuery, key, val, opts)
+}
+
+func (s *Sqlite) GetVersion(ctx context.Context, key string) ([]byte, cacher.Version, error) {
+	var val string
+	var version int64
+	err := s.db.QueryRowContext(ctx, "SELECT value, version FROM cache WHERE key = ? AND expires_at > DATETIME('now')", key).Scan(&val, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, cacher.ErrKeyNotFound
+		}
+		return nil, nil, err
+	}
+	return []byte(val), version, nil
+}
+
+func (s *Sqlite) CompareAndSwap(ctx context.Context, key string, val []byte, version cacher.Version, opts ...cacher.StoreOptions) error {
+	swapped, err := s.setIf(ctx, ReplaceQuery+"AND version = ?4", key, val, opts, version)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return cacher.ErrConflict
+	}
 	return nil
 }
 
+func (s *Sqlite) setIf(ctx context.Context, query string, key string, val []byte, opts []cacher.StoreOptions, args ...any) (bool, error) {
+	var opt cacher.StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	written, err := s.put(ctx, tx, query, key, val, opt, args...)
+	if err != nil || !written {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *Sqlite) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return vals, nil
+	}
+
+	in, args := InClause(keys)
+	rows, err := s.db.QueryContext(ctx, "SELECT key, value FROM cache WHERE key IN "+in+" AND expires_at > DATETIME('now')", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string][]byte, len(keys))
+	for rows.Next() {
+		var key, val string
+		if err := rows.Scan(&key, &val); err != nil {
+			return nil, err
+		}
+		found[key] = []byte(val)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		vals[i] = found[key]
+	}
+	return vals, nil
+}
+
+func (s *Sqlite) MSet(ctx context.Context, params ...cacher.StoreParams) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, param := range params {
+		if err := s.set(ctx, tx, param.Key, param.Value, param.Options); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Sqlite) MDelete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	in, args := InClause(keys)
+	_, err = tx.ExecContext(ctx, "DELETE FROM cache WHERE key IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM cache_tags WHERE key IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Sqlite) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	in, args := InClause(tags)
+	_, err = tx.ExecContext(ctx, "DELETE FROM cache WHERE key IN (SELECT key FROM cache_tags WHERE tag IN "+in+")", args...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM cache_tags WHERE tag IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *Sqlite) Get(ctx context.Context, key string) ([]byte, error) {
 	// Handler
 	var val string
@@ -91,6 +335,10 @@ func (s *Sqlite) Delete(ctx context.Context, key string) error {
 	if err != nil {
 		return err
 	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM cache_tags WHERE key = ?", key)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -101,6 +349,148 @@ func (s *Sqlite) Clear(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM cache_tags")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Sqlite) IncrBy(ctx context.Context, key string, delta int64, opts ...cacher.StoreOptions) (int64, error) {
+	ttl := s.ttl
+	if len(opts) > 0 && opts[0].Ttl > 0 {
+		ttl = opts[0].Ttl
+	}
+
+	var n int64
+	err := s.db.QueryRowContext(ctx, IncrByQuery, key, delta, ParseTimestap(ttl)).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Sqlite) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error) {
+	var token int64
+	err := s.db.QueryRowContext(ctx, AcquireQuery, key, owner, ParseTimestap(ttl)).Scan(&token)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return token, nil
+}
+
+func (s *Sqlite) Release(ctx context.Context, key string, owner string) error {
+	return s.lock(ctx, key, owner, time.Time{})
+}
+
+func (s *Sqlite) Extend(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	return s.lock(ctx, key, owner, ParseTimestap(ttl))
+}
+
+// lock moves the expiry of a lock still held by owner.
+func (s *Sqlite) lock(ctx context.Context, key string, owner string, expiresAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE cache_locks SET expires_at = ? WHERE name = ? AND owner = ? AND expires_at > DATETIME('now')", expiresAt, key, owner)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return cacher.ErrLockNotHeld
+	}
+	return nil
+}
+
+func (s *Sqlite) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT expires_at FROM cache WHERE key = ? AND expires_at > DATETIME('now')", key).Scan(&expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, cacher.ErrKeyNotFound
+		}
+		return 0, err
+	}
+	if !expiresAt.Before(NoExpiry) {
+		return cacher.NoTtl, nil
+	}
+	return time.Until(expiresAt), nil
+}
+
+func (s *Sqlite) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.expire(ctx, key, ParseTimestap(ttl))
+}
+
+func (s *Sqlite) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return s.expire(ctx, key, at.UTC())
+}
+
+func (s *Sqlite) Persist(ctx context.Context, key string) error {
+	return s.expire(ctx, key, NoExpiry)
+}
+
+func (s *Sqlite) Touch(ctx context.Context, key string) error {
+	if s.ttl <= 0 {
+		return s.Persist(ctx, key)
+	}
+	return s.expire(ctx, key, ParseTimestap(s.ttl))
+}
+
+func (s *Sqlite) expire(ctx context.Context, key string, expiresAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE cache SET expires_at = ? WHERE key = ? AND expires_at > DATETIME('now')", expiresAt, key)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return cacher.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *Sqlite) Keys(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT key FROM cache WHERE "+PrefixClause+" AND expires_at > DATETIME('now')", PrefixArgs(prefix)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *Sqlite) Count(ctx context.Context, prefix string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cache WHERE "+PrefixClause+" AND expires_at > DATETIME('now')", PrefixArgs(prefix)...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *Sqlite) ClearPrefix(ctx context.Context, prefix string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM cache WHERE "+PrefixClause, PrefixArgs(prefix)...)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM cache_tags WHERE "+PrefixClause, PrefixArgs(prefix)...)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -109,6 +499,7 @@ func (s *Sqlite) gc(sleep time.Duration) {
 	defer ticker.Stop()
 	for range ticker.C {
 		s.db.Exec("DELETE FROM cache WHERE expires_at < DATETIME('now')")
+		s.db.Exec("DELETE FROM cache_tags WHERE key NOT IN (SELECT key FROM cache)")
 	}
 }
 
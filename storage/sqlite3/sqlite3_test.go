@@ -175,3 +175,121 @@ func Test_Module(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, "John", response.Data)
 }
+
+func Test_ClearPrefix(t *testing.T) {
+	cache := sqlite3.New(sqlite3.Options{
+		Addr: "test.db",
+		Ttl:  15 * time.Minute,
+	})
+	store, ok := cache.(cacher.PrefixStore)
+	require.True(t, ok)
+
+	ctx := context.Background()
+	require.Nil(t, cache.Clear(ctx))
+	require.Nil(t, cache.Set(ctx, "users:1", []byte("John")))
+	require.Nil(t, cache.Set(ctx, "users:2", []byte("Jane")))
+	require.Nil(t, cache.Set(ctx, "Users:3", []byte("Jack")))
+	require.Nil(t, cache.Set(ctx, "users_4", []byte("Jill")))
+
+	count, err := store.Count(ctx, "users:")
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	keys, err := store.Keys(ctx, "users:")
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"users:1", "users:2"}, keys)
+
+	err = store.ClearPrefix(ctx, "users:")
+	require.Nil(t, err)
+
+	data, err := cache.Get(ctx, "users:1")
+	require.Nil(t, err)
+	require.Empty(t, data)
+
+	data, err = cache.Get(ctx, "Users:3")
+	require.Nil(t, err)
+	require.Equal(t, []byte("Jack"), data)
+}
+
+func Test_IncrBy(t *testing.T) {
+	cache := sqlite3.New(sqlite3.Options{
+		Addr: "test.db",
+		Ttl:  15 * time.Minute,
+	})
+	store, ok := cache.(cacher.CounterStore)
+	require.True(t, ok)
+
+	ctx := context.Background()
+	require.Nil(t, cache.Delete(ctx, "views"))
+
+	n, err := store.IncrBy(ctx, "views", 5)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), n)
+
+	n, err = store.IncrBy(ctx, "views", -2)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), n)
+
+	data, err := cache.Get(ctx, "views")
+	require.Nil(t, err)
+	require.Equal(t, []byte("3"), data)
+}
+
+func Test_Conditional(t *testing.T) {
+	store := sqlite3.New(sqlite3.Options{
+		Addr: "test.db",
+		Ttl:  time.Minute,
+	})
+	require.NotNil(t, store)
+	ctx := context.Background()
+	require.Nil(t, store.Clear(ctx))
+
+	schema := cacher.NewSchema[int](cacher.Config{Store: store})
+
+	ok, err := schema.Replace("count", 1)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ok, err = schema.SetNX("count", 1)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	ok, err = schema.SetNX("count", 2)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	n, err := schema.Update("count", func(old int) (int, error) {
+		return old + 1, nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+
+	conditional := store.(cacher.ConditionalStore)
+	_, version, err := conditional.GetVersion(ctx, "count")
+	require.Nil(t, err)
+	require.Nil(t, schema.Set("count", 5))
+	require.ErrorIs(t, conditional.CompareAndSwap(ctx, "count", []byte("6"), version), cacher.ErrConflict)
+}
+
+func Test_Lock(t *testing.T) {
+	store := sqlite3.New(sqlite3.Options{
+		Addr: "test.db",
+	})
+	require.NotNil(t, store)
+	ctx := context.Background()
+
+	lease, err := cacher.TryLock(ctx, store, "report", 5*time.Second)
+	require.Nil(t, err)
+
+	_, err = cacher.TryLock(ctx, store, "report", 5*time.Second)
+	require.ErrorIs(t, err, cacher.ErrLocked)
+
+	require.Nil(t, lease.Extend(ctx, 10*time.Second))
+	require.Nil(t, lease.Unlock(ctx))
+	require.ErrorIs(t, lease.Unlock(ctx), cacher.ErrLockNotHeld)
+
+	next, err := cacher.TryLock(ctx, store, "report", 5*time.Second)
+	require.Nil(t, err)
+	require.Greater(t, next.Token(), lease.Token())
+	require.Nil(t, next.Unlock(ctx))
+}
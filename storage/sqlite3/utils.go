@@ -1,10 +1,33 @@
 package sqlite3
 
 import (
+	"strings"
 	"time"
 )
 
+// NoExpiry is the expires_at of keys that never expire.
+var NoExpiry = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
 func ParseTimestap(ttl time.Duration) time.Time {
 	expiresAt := time.Now().UTC().Add(time.Duration(ttl))
 	return expiresAt
 }
+
+// PrefixClause matches keys starting with a prefix. LIKE narrows the rows
+// and the substr comparison keeps the match case sensitive.
+const PrefixClause = `key LIKE ? ESCAPE '\' AND substr(key, 1, length(?)) = ?`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func PrefixArgs(prefix string) []any {
+	return []any{likeEscaper.Replace(prefix) + "%", prefix, prefix}
+}
+
+// InClause returns a "(?, ?, ...)" placeholder list and its args for values.
+func InClause(values []string) (string, []any) {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
@@ -2,12 +2,31 @@ package cacher
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var ErrNotSupported = errors.New("operation not supported by store")
+
+// ErrConflict is returned when a compare-and-swap finds the value changed
+// or removed since its version was read.
+var ErrConflict = errors.New("value changed since it was read")
+
+// NoTtl is the ttl reported for keys that never expire.
+const NoTtl time.Duration = -1
+
 type StoreOptions struct {
 	Ttl      time.Duration
 	MaxItems int
+	// Tags lets InvalidateTags remove the value along with every other
+	// value sharing one of them.
+	Tags []string
+}
+
+type StoreParams struct {
+	Key     string
+	Value   []byte
+	Options StoreOptions
 }
 
 type Store interface {
@@ -17,3 +36,82 @@ type Store interface {
 	Delete(ctx context.Context, key string) error
 	Clear(ctx context.Context) error
 }
+
+// PrefixStore is implemented by stores that can list, count and remove
+// the keys starting with a prefix without touching the rest of the store.
+type PrefixStore interface {
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Count(ctx context.Context, prefix string) (int, error)
+	ClearPrefix(ctx context.Context, prefix string) error
+}
+
+// TagStore is implemented by stores that can remove every value written
+// with a tag in StoreOptions.Tags.
+type TagStore interface {
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// BatchStore is implemented by stores that read, write and delete many
+// keys in one round trip. MGet returns one value per key, nil for misses.
+type BatchStore interface {
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	MSet(ctx context.Context, params ...StoreParams) error
+	MDelete(ctx context.Context, keys ...string) error
+}
+
+// TtlStore is implemented by stores that can read and change the ttl of a
+// key without rewriting its value. Missing keys return ErrKeyNotFound and
+// Touch resets the ttl to the store default.
+type TtlStore interface {
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	ExpireAt(ctx context.Context, key string, at time.Time) error
+	Persist(ctx context.Context, key string) error
+	Touch(ctx context.Context, key string) error
+}
+
+// GetTtlStore is implemented by stores that read a value along with its
+// ttl in one round trip. Keys that never expire report NoTtl and missing
+// keys return ErrKeyNotFound or a nil value, as Get does.
+type GetTtlStore interface {
+	GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+// CounterStore is implemented by stores that increment integer values
+// atomically. A missing key starts from 0 and gets the ttl in opts, an
+// existing key keeps its ttl.
+type CounterStore interface {
+	IncrBy(ctx context.Context, key string, delta int64, opts ...StoreOptions) (int64, error)
+}
+
+// Version identifies the value a key held when it was read. Its content
+// is specific to each store.
+type Version any
+
+// ConditionalStore is implemented by stores that write only under a
+// condition. SetNX writes missing keys, Replace writes existing ones, and
+// CompareAndSwap writes only while the key still holds the version read
+// by GetVersion, returning ErrConflict otherwise. GetVersion may return a
+// version with a nil value for a key holding a value it no longer serves.
+type ConditionalStore interface {
+	SetNX(ctx context.Context, key string, value []byte, opts ...StoreOptions) (bool, error)
+	Replace(ctx context.Context, key string, value []byte, opts ...StoreOptions) (bool, error)
+	GetVersion(ctx context.Context, key string) ([]byte, Version, error)
+	CompareAndSwap(ctx context.Context, key string, value []byte, version Version, opts ...StoreOptions) error
+}
+
+// EventStore is implemented by stores that remove values on their own and
+// report it, as Evict or Expire, to the subscribed handlers.
+type EventStore interface {
+	Subscribe(handler func(hook HookKey, event Event)) (cancel func())
+}
+
+// LockStore is implemented by stores that can hold locks for Lock. Acquire
+// takes key for owner and returns a fencing token greater than any earlier
+// one for key, or 0 while another owner holds it. Release and Extend return
+// ErrLockNotHeld unless owner still holds key.
+type LockStore interface {
+	Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error)
+	Release(ctx context.Context, key string, owner string) error
+	Extend(ctx context.Context, key string, owner string, ttl time.Duration) error
+}
@@ -0,0 +1,18 @@
+package cacher
+
+// InvalidateTags removes every value written with one of tags, across all
+// namespaces sharing the store.
+func (s *Schema[M]) InvalidateTags(tags ...string) (err error) {
+	s, end := s.trace("invalidate_tags")
+	defer end(&err)
+
+	store, ok := As[TagStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := store.InvalidateTags(s.ctx, tags...); err != nil {
+		return err
+	}
+	s.publish(InvalidateTagged, tags...)
+	return nil
+}
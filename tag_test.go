@@ -0,0 +1,52 @@
+package cacher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_InvalidateTags(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{
+		Ttl: 15 * time.Minute,
+	})
+	pages := cacher.NewSchema[string](cacher.Config{
+		Store:     store,
+		Namespace: "pages",
+	})
+	lists := cacher.NewSchema[[]string](cacher.Config{
+		Store:     store,
+		Namespace: "lists",
+	})
+
+	err := pages.Set("42", "Product 42", cacher.StoreOptions{Tags: []string{"product:42"}})
+	require.Nil(t, err)
+	err = pages.Set("7", "Product 7", cacher.StoreOptions{Tags: []string{"product:7"}})
+	require.Nil(t, err)
+	err = lists.Set("home", []string{"42", "7"}, cacher.StoreOptions{Tags: []string{"product:42", "product:7"}})
+	require.Nil(t, err)
+
+	err = pages.InvalidateTags("product:42")
+	require.Nil(t, err)
+
+	_, err = pages.Get("42")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	_, err = lists.Get("home")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	data, err := pages.Get("7")
+	require.Nil(t, err)
+	require.Equal(t, "Product 7", data)
+
+	// Rewriting without tags detaches the value from its old tags.
+	err = pages.Set("7", "Product 7 v2")
+	require.Nil(t, err)
+	err = pages.InvalidateTags("product:7")
+	require.Nil(t, err)
+
+	data, err = pages.Get("7")
+	require.Nil(t, err)
+	require.Equal(t, "Product 7 v2", data)
+}
@@ -0,0 +1,397 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type TieredOptions struct {
+	// L1 is the local store read first, a Memory store when nil.
+	L1 Store
+	// L2 is the shared store L1 reads through to.
+	L2 Store
+	// L1Ttl caps how long values live in L1, 0 means a minute.
+	L1Ttl time.Duration
+}
+
+// TieredStore serves reads from L1 and reads through to L2 on a miss,
+// backfilling L1 for at most L1Ttl, and what the value has left in L2 when
+// L2 implements GetTtlStore.
+// Writes go to L2 then L1, and deletes remove from both. Other instances'
+// writes only show in L1 once its copy expires. Optional interfaces are
+// served by L2 and drop the key from L1.
+type TieredStore struct {
+	l1    Store
+	l2    Store
+	l1Ttl time.Duration
+}
+
+func NewTiered(opt TieredOptions) Store {
+	if opt.L1 == nil {
+		opt.L1 = NewInMemory(StoreOptions{})
+	}
+	if opt.L1Ttl <= 0 {
+		opt.L1Ttl = time.Minute
+	}
+	return &TieredStore{
+		l1:    opt.L1,
+		l2:    opt.L2,
+		l1Ttl: opt.L1Ttl,
+	}
+}
+
+func (t *TieredStore) Name() string {
+	return TIERED
+}
+
+func (t *TieredStore) L1() Store {
+	return t.l1
+}
+
+func (t *TieredStore) L2() Store {
+	return t.l2
+}
+
+func (t *TieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := t.l1.Get(ctx, key)
+	if err == nil && val != nil {
+		return val, nil
+	}
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+
+	store, ok := As[GetTtlStore](t.l2)
+	if !ok {
+		val, err = t.l2.Get(ctx, key)
+		if err != nil || val == nil {
+			return val, err
+		}
+		t.backfill(ctx, key, val, StoreOptions{})
+		return val, nil
+	}
+
+	val, ttl, err := store.GetWithTTL(ctx, key)
+	if err != nil || val == nil {
+		return val, err
+	}
+	// a value about to expire in L2 is not worth copying
+	if ttl != 0 {
+		t.backfill(ctx, key, val, StoreOptions{Ttl: max(ttl, 0)})
+	}
+	return val, nil
+}
+
+func (t *TieredStore) Set(ctx context.Context, key string, value []byte, opts ...StoreOptions) error {
+	err := t.l2.Set(ctx, key, value, opts...)
+	if err != nil {
+		return err
+	}
+	var opt StoreOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	t.backfill(ctx, key, value, opt)
+	return nil
+}
+
+// backfill copies a value into L1, keeping it no longer than L1Ttl or its
+// own ttl.
+func (t *TieredStore) backfill(ctx context.Context, key string, value []byte, opt StoreOptions) {
+	if opt.Ttl <= 0 || opt.Ttl > t.l1Ttl {
+		opt.Ttl = t.l1Ttl
+	}
+	t.l1.Set(ctx, key, value, opt)
+}
+
+func (t *TieredStore) Delete(ctx context.Context, key string) error {
+	err := t.l2.Delete(ctx, key)
+	if err != nil {
+		return err
+	}
+	return t.l1.Delete(ctx, key)
+}
+
+func (t *TieredStore) Clear(ctx context.Context) error {
+	err := t.l2.Clear(ctx)
+	if err != nil {
+		return err
+	}
+	return t.l1.Clear(ctx)
+}
+
+func (t *TieredStore) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	vals := make([][]byte, len(keys))
+	missed := []string{}
+	for i, key := range keys {
+		val, err := t.l1.Get(ctx, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+		if val == nil {
+			missed = append(missed, key)
+		}
+		vals[i] = val
+	}
+	if len(missed) == 0 {
+		return vals, nil
+	}
+
+	found, err := mget(ctx, t.l2, missed)
+	if err != nil {
+		return nil, err
+	}
+	j := 0
+	for i := range vals {
+		if vals[i] != nil {
+			continue
+		}
+		vals[i] = found[j]
+		// one batch cannot tell the ttls, so these copies keep L1Ttl
+		if found[j] != nil {
+			t.backfill(ctx, keys[i], found[j], StoreOptions{})
+		}
+		j++
+	}
+	return vals, nil
+}
+
+func (t *TieredStore) MSet(ctx context.Context, params ...StoreParams) error {
+	store, ok := As[BatchStore](t.l2)
+	if !ok {
+		for _, param := range params {
+			if err := t.Set(ctx, param.Key, param.Value, param.Options); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := store.MSet(ctx, params...)
+	if err != nil {
+		return err
+	}
+	for _, param := range params {
+		t.backfill(ctx, param.Key, param.Value, param.Options)
+	}
+	return nil
+}
+
+func (t *TieredStore) MDelete(ctx context.Context, keys ...string) error {
+	store, ok := As[BatchStore](t.l2)
+	if !ok {
+		for _, key := range keys {
+			if err := t.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := store.MDelete(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	t.evict(ctx, keys...)
+	return nil
+}
+
+// evict drops keys from L1 after L2 changed them in place.
+func (t *TieredStore) evict(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		t.l1.Delete(ctx, key)
+	}
+}
+
+func (t *TieredStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	store, ok := As[PrefixStore](t.l2)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return store.Keys(ctx, prefix)
+}
+
+func (t *TieredStore) Count(ctx context.Context, prefix string) (int, error) {
+	store, ok := As[PrefixStore](t.l2)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return store.Count(ctx, prefix)
+}
+
+func (t *TieredStore) ClearPrefix(ctx context.Context, prefix string) error {
+	store, ok := As[PrefixStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	err := store.ClearPrefix(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if local, ok := As[PrefixStore](t.l1); ok {
+		return local.ClearPrefix(ctx, prefix)
+	}
+	return t.l1.Clear(ctx)
+}
+
+// InvalidateTags clears L1 entirely, since values backfilled from L2 do
+// not carry their tags.
+func (t *TieredStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	store, ok := As[TagStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	err := store.InvalidateTags(ctx, tags...)
+	if err != nil {
+		return err
+	}
+	return t.l1.Clear(ctx)
+}
+
+func (t *TieredStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	store, ok := As[TtlStore](t.l2)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return store.TTL(ctx, key)
+}
+
+func (t *TieredStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return t.ttl(ctx, key, func(store TtlStore) error {
+		return store.Expire(ctx, key, ttl)
+	})
+}
+
+func (t *TieredStore) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return t.ttl(ctx, key, func(store TtlStore) error {
+		return store.ExpireAt(ctx, key, at)
+	})
+}
+
+func (t *TieredStore) Persist(ctx context.Context, key string) error {
+	return t.ttl(ctx, key, func(store TtlStore) error {
+		return store.Persist(ctx, key)
+	})
+}
+
+func (t *TieredStore) Touch(ctx context.Context, key string) error {
+	return t.ttl(ctx, key, func(store TtlStore) error {
+		return store.Touch(ctx, key)
+	})
+}
+
+func (t *TieredStore) ttl(ctx context.Context, key string, fnc func(store TtlStore) error) error {
+	store, ok := As[TtlStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	err := fnc(store)
+	if err != nil {
+		return err
+	}
+	t.evict(ctx, key)
+	return nil
+}
+
+func (t *TieredStore) IncrBy(ctx context.Context, key string, delta int64, opts ...StoreOptions) (int64, error) {
+	store, ok := As[CounterStore](t.l2)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	n, err := store.IncrBy(ctx, key, delta, opts...)
+	if err != nil {
+		return 0, err
+	}
+	t.evict(ctx, key)
+	return n, nil
+}
+
+func (t *TieredStore) SetNX(ctx context.Context, key string, value []byte, opts ...StoreOptions) (bool, error) {
+	store, ok := As[ConditionalStore](t.l2)
+	if !ok {
+		return false, ErrNotSupported
+	}
+	written, err := store.SetNX(ctx, key, value, opts...)
+	if err != nil {
+		return false, err
+	}
+	t.evict(ctx, key)
+	return written, nil
+}
+
+func (t *TieredStore) Replace(ctx context.Context, key string, value []byte, opts ...StoreOptions) (bool, error) {
+	store, ok := As[ConditionalStore](t.l2)
+	if !ok {
+		return false, ErrNotSupported
+	}
+	written, err := store.Replace(ctx, key, value, opts...)
+	if err != nil {
+		return false, err
+	}
+	t.evict(ctx, key)
+	return written, nil
+}
+
+// GetVersion reads L2, the only tier whose versions CompareAndSwap checks.
+func (t *TieredStore) GetVersion(ctx context.Context, key string) ([]byte, Version, error) {
+	store, ok := As[ConditionalStore](t.l2)
+	if !ok {
+		return nil, nil, ErrNotSupported
+	}
+	return store.GetVersion(ctx, key)
+}
+
+func (t *TieredStore) CompareAndSwap(ctx context.Context, key string, value []byte, version Version, opts ...StoreOptions) error {
+	store, ok := As[ConditionalStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	err := store.CompareAndSwap(ctx, key, value, version, opts...)
+	if err != nil {
+		return err
+	}
+	t.evict(ctx, key)
+	return nil
+}
+
+func (t *TieredStore) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (int64, error) {
+	store, ok := As[LockStore](t.l2)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return store.Acquire(ctx, key, owner, ttl)
+}
+
+func (t *TieredStore) Release(ctx context.Context, key string, owner string) error {
+	store, ok := As[LockStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	return store.Release(ctx, key, owner)
+}
+
+func (t *TieredStore) Extend(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	store, ok := As[LockStore](t.l2)
+	if !ok {
+		return ErrNotSupported
+	}
+	return store.Extend(ctx, key, owner, ttl)
+}
+
+// mget reads keys from store, nil for misses, in one round trip when the
+// store supports it.
+func mget(ctx context.Context, store Store, keys []string) ([][]byte, error) {
+	if batch, ok := As[BatchStore](store); ok {
+		return batch.MGet(ctx, keys...)
+	}
+	vals := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := store.Get(ctx, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
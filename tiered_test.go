@@ -0,0 +1,115 @@
+package cacher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Tiered(t *testing.T) {
+	ctx := context.Background()
+	l1 := cacher.NewInMemory(cacher.StoreOptions{})
+	l2 := cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Hour})
+	store := cacher.NewTiered(cacher.TieredOptions{
+		L1:    l1,
+		L2:    l2,
+		L1Ttl: 10 * time.Second,
+	})
+	schema := cacher.NewSchema[string](cacher.Config{Store: store})
+
+	// writes go through to both tiers
+	require.Nil(t, schema.Set("1", "John", cacher.StoreOptions{Ttl: time.Hour}))
+	_, err := l1.Get(ctx, "1")
+	require.Nil(t, err)
+	_, err = l2.Get(ctx, "1")
+	require.Nil(t, err)
+
+	ttl, err := l1.(cacher.TtlStore).TTL(ctx, "1")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, 10*time.Second)
+
+	// misses read through to L2 and backfill L1
+	require.Nil(t, l1.Delete(ctx, "1"))
+	data, err := schema.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+	_, err = l1.Get(ctx, "1")
+	require.Nil(t, err)
+
+	// backfills never outlive the value in L2
+	require.Nil(t, l2.Set(ctx, "2", []byte("Jane"), cacher.StoreOptions{Ttl: 2 * time.Second}))
+	_, err = store.Get(ctx, "2")
+	require.Nil(t, err)
+	ttl, err = l1.(cacher.TtlStore).TTL(ctx, "2")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, 2*time.Second)
+
+	vals, err := schema.MGet("1")
+	require.Nil(t, err)
+	require.Equal(t, []string{"John"}, vals)
+
+	// deletes remove from both
+	require.Nil(t, schema.Delete("1"))
+	_, err = l1.Get(ctx, "1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	_, err = l2.Get(ctx, "1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	// changes made in L2 in place drop the L1 copy
+	counter := cacher.NewCounter(cacher.Config{Store: store})
+	require.Nil(t, counter.Set("views", 1))
+	n, err := counter.Incr("views")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+	n, err = counter.Get("views")
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+}
+
+type l2Reads struct {
+	*cacher.Memory
+	reads int
+}
+
+func (s *l2Reads) Get(ctx context.Context, key string) ([]byte, error) {
+	s.reads++
+	return s.Memory.Get(ctx, key)
+}
+
+func (s *l2Reads) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	s.reads++
+	return s.Memory.GetWithTTL(ctx, key)
+}
+
+func (s *l2Reads) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s.reads++
+	return s.Memory.TTL(ctx, key)
+}
+
+func (s *l2Reads) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	s.reads++
+	return s.Memory.MGet(ctx, keys...)
+}
+
+func Test_TieredReads(t *testing.T) {
+	ctx := context.Background()
+	l2 := &l2Reads{Memory: cacher.NewInMemory(cacher.StoreOptions{Ttl: time.Hour}).(*cacher.Memory)}
+	store := cacher.NewTiered(cacher.TieredOptions{L2: l2})
+	require.Nil(t, l2.Memory.Set(ctx, "1", []byte("John")))
+	require.Nil(t, l2.Memory.Set(ctx, "2", []byte("Jane")))
+	require.Nil(t, l2.Memory.Set(ctx, "3", []byte("Jack")))
+
+	// a miss reads the value and its ttl at once
+	_, err := store.Get(ctx, "1")
+	require.Nil(t, err)
+	require.Equal(t, 1, l2.reads)
+
+	// and a batch of misses stays one read
+	vals, err := store.(cacher.BatchStore).MGet(ctx, "1", "2", "3")
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("John"), []byte("Jane"), []byte("Jack")}, vals)
+	require.Equal(t, 2, l2.reads)
+}
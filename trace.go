@@ -0,0 +1,186 @@
+package cacher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// Attributes set on the spans of schema operations.
+const (
+	AttrStore     = "cache.store"
+	AttrNamespace = "cache.namespace"
+	AttrKey       = "cache.key"
+	AttrKeys      = "cache.keys"
+	AttrHit       = "cache.hit"
+	AttrHits      = "cache.hits"
+	AttrMisses    = "cache.misses"
+	AttrValueSize = "cache.value_size"
+)
+
+// Tracer opens a span for every schema operation, as a child of the span
+// in the schema context. See NewRecorder and the tracing/otel adapter.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced operation in progress.
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// spanState gathers what a traced operation reads and writes, set on its
+// span once it ends.
+type spanState struct {
+	span   Span
+	hits   int
+	misses int
+	size   int
+}
+
+// WithCtx returns a copy of the schema running its operations in ctx, so
+// each request can carry its own context and spans. The copy shares the
+// store, hooks and loads of the schema.
+func (s *Schema[M]) WithCtx(ctx context.Context) *Schema[M] {
+	c := *s
+	c.ctx = ctx
+	c.unwatch = nil
+	c.traced = nil
+	return &c
+}
+
+// trace returns a copy of s whose operation op runs in a new span, along
+// with the function ending it with the operation error.
+func (s *Schema[M]) trace(op string, keys ...string) (*Schema[M], func(*error)) {
+	if s.Tracer == nil {
+		return s, func(*error) {}
+	}
+
+	ctx, span := s.Tracer.Start(s.ctx, "cacher."+op)
+	span.SetAttribute(AttrStore, s.Store.Name())
+	if s.Namespace != "" {
+		span.SetAttribute(AttrNamespace, s.Namespace)
+	}
+	if len(keys) == 1 {
+		if key := s.traceKey(keys[0]); key != "" {
+			span.SetAttribute(AttrKey, key)
+		}
+	} else if len(keys) > 1 {
+		span.SetAttribute(AttrKeys, len(keys))
+	}
+
+	c := s.WithCtx(ctx)
+	c.traced = &spanState{span: span}
+	return c, func(err *error) {
+		state := c.traced
+		if state.hits+state.misses == 1 {
+			span.SetAttribute(AttrHit, state.hits == 1)
+		} else if state.hits+state.misses > 1 {
+			span.SetAttribute(AttrHits, state.hits)
+			span.SetAttribute(AttrMisses, state.misses)
+		}
+		if state.size > 0 {
+			span.SetAttribute(AttrValueSize, state.size)
+		}
+		if *err != nil && !errors.Is(*err, ErrKeyNotFound) {
+			span.RecordError(*err)
+		}
+		span.End()
+	}
+}
+
+func (s *Schema[M]) traceKey(key string) string {
+	if s.TraceKey != nil {
+		return s.TraceKey(key)
+	}
+	sum := sha256.Sum256([]byte(s.generateKey(key)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Recorder is a Tracer keeping the spans it ends, for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+	ids   int
+}
+
+// RecordedSpan is a span ended by a Recorder. Parent is the ID of the span
+// it was started in, 0 for root spans.
+type RecordedSpan struct {
+	ID         int
+	Parent     int
+	Name       string
+	Attributes map[string]any
+	Err        error
+}
+
+type recordingSpan struct {
+	recorder *Recorder
+	mu       sync.Mutex
+	span     RecordedSpan
+}
+
+type recorderKey struct{}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	r.mu.Lock()
+	r.ids++
+	span := &recordingSpan{
+		recorder: r,
+		span: RecordedSpan{
+			ID:         r.ids,
+			Name:       name,
+			Attributes: make(map[string]any),
+		},
+	}
+	r.mu.Unlock()
+
+	if parent, ok := ctx.Value(recorderKey{}).(*recordingSpan); ok {
+		span.span.Parent = parent.span.ID
+	}
+	return context.WithValue(ctx, recorderKey{}, span), span
+}
+
+// Spans returns the ended spans in the order they ended.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// Reset drops the recorded spans.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	s.span.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.mu.Lock()
+	s.span.Err = err
+	s.mu.Unlock()
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	span := s.span
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	s.recorder.spans = append(s.recorder.spans, span)
+	s.recorder.mu.Unlock()
+}
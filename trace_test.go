@@ -0,0 +1,74 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Trace(t *testing.T) {
+	recorder := cacher.NewRecorder()
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "users",
+		Tracer:    recorder,
+	})
+
+	ctx, request := recorder.Start(context.Background(), "request")
+	users := cache.WithCtx(ctx)
+	require.Nil(t, users.Set("a", "1"))
+	_, err := users.Get("a")
+	require.Nil(t, err)
+	_, err = users.Get("b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	_, err = users.MGet("a", "b")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	request.End()
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 5)
+	root := spans[4]
+	require.Equal(t, "request", root.Name)
+	for _, span := range spans[:4] {
+		require.Equal(t, root.ID, span.Parent)
+		require.Equal(t, "memory_cache_manager", span.Attributes[cacher.AttrStore])
+		require.Equal(t, "users", span.Attributes[cacher.AttrNamespace])
+		require.Nil(t, span.Err)
+	}
+
+	set, hit, miss, mget := spans[0], spans[1], spans[2], spans[3]
+	require.Equal(t, "cacher.set", set.Name)
+	require.Greater(t, set.Attributes[cacher.AttrValueSize], 0)
+	require.Equal(t, "cacher.get", hit.Name)
+	require.Equal(t, true, hit.Attributes[cacher.AttrHit])
+	require.Equal(t, set.Attributes[cacher.AttrKey], hit.Attributes[cacher.AttrKey])
+	require.NotContains(t, hit.Attributes[cacher.AttrKey], "a")
+	require.Equal(t, false, miss.Attributes[cacher.AttrHit])
+	require.NotEqual(t, hit.Attributes[cacher.AttrKey], miss.Attributes[cacher.AttrKey])
+	require.Equal(t, "cacher.mget", mget.Name)
+	require.Equal(t, 2, mget.Attributes[cacher.AttrKeys])
+	require.Equal(t, 1, mget.Attributes[cacher.AttrHits])
+	require.Equal(t, 1, mget.Attributes[cacher.AttrMisses])
+
+	recorder.Reset()
+	failed := errors.New("down")
+	broken := cacher.NewSchema[string](cacher.Config{
+		Store:  &failingStore{Store: cacher.NewInMemory(cacher.StoreOptions{}), err: failed},
+		Tracer: recorder,
+		TraceKey: func(key string) string {
+			return "redacted"
+		},
+	})
+	require.ErrorIs(t, broken.Set("a", "1"), failed)
+	spans = recorder.Spans()
+	require.Len(t, spans, 1)
+	require.Equal(t, 0, spans[0].Parent)
+	require.Equal(t, "redacted", spans[0].Attributes[cacher.AttrKey])
+	require.ErrorIs(t, spans[0].Err, failed)
+}
@@ -0,0 +1,59 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinh-tinh/cacher/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a cacher.Tracer opening client spans with tracer.
+func New(tracer trace.Tracer) cacher.Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, cacher.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &Span{span: span}
+}
+
+type Span struct {
+	span trace.Span
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	s.span.SetAttributes(attr(key, value))
+}
+
+func (s *Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *Span) End() {
+	s.span.End()
+}
+
+func attr(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
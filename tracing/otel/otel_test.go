@@ -0,0 +1,55 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/tracing/otel"
+	"github.com/tinh-tinh/cacher/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_Tracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("cacher")
+
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "users",
+		Tracer:    otel.New(tracer),
+	})
+
+	ctx, request := tracer.Start(context.Background(), "request")
+	_, err := cache.WithCtx(ctx).Get("a")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	request.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	get := spans[0]
+	require.Equal(t, "cacher.get", get.Name())
+	require.Equal(t, request.SpanContext().SpanID(), get.Parent().SpanID())
+	require.Contains(t, get.Attributes(), attribute.String(cacher.AttrNamespace, "users"))
+	require.Contains(t, get.Attributes(), attribute.Bool(cacher.AttrHit, false))
+	require.Equal(t, codes.Unset, get.Status().Code)
+
+	span := otel.New(tracer)
+	_, s := span.Start(context.Background(), "cacher.set")
+	s.SetAttribute(cacher.AttrValueSize, 12)
+	s.RecordError(errors.New("down"))
+	s.End()
+
+	set := recorder.Ended()[2]
+	require.Contains(t, set.Attributes(), attribute.Int(cacher.AttrValueSize, 12))
+	require.Equal(t, codes.Error, set.Status().Code)
+	require.Equal(t, "down", set.Status().Description)
+}
@@ -0,0 +1,87 @@
+package cacher
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTtlPolicy is returned when changing the ttl of a key on a schema with
+// StaleTtl or Recompute, whose values carry their own expiry.
+var ErrTtlPolicy = errors.New("ttl of values written with StaleTtl or Recompute cannot change")
+
+// TTL returns how long key has left, or NoTtl when it never expires.
+func (s *Schema[M]) TTL(key string) (_ time.Duration, err error) {
+	s, end := s.trace("ttl", key)
+	defer end(&err)
+
+	store, ok := As[TtlStore](s.Store)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return store.TTL(s.ctx, s.generateKey(key))
+}
+
+// Expire sets key to expire after ttl. Expire, ExpireAt, Persist and
+// Touch return ErrTtlPolicy on schemas with StaleTtl or Recompute.
+func (s *Schema[M]) Expire(key string, ttl time.Duration) (err error) {
+	s, end := s.trace("expire", key)
+	defer end(&err)
+
+	if s.StaleTtl > 0 || s.Recompute != nil {
+		return ErrTtlPolicy
+	}
+	store, ok := As[TtlStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	return store.Expire(s.ctx, s.generateKey(key), ttl)
+}
+
+// ExpireAt sets key to expire at a point in time.
+func (s *Schema[M]) ExpireAt(key string, at time.Time) (err error) {
+	s, end := s.trace("expire_at", key)
+	defer end(&err)
+
+	if s.StaleTtl > 0 || s.Recompute != nil {
+		return ErrTtlPolicy
+	}
+	store, ok := As[TtlStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	return store.ExpireAt(s.ctx, s.generateKey(key), at)
+}
+
+// Persist removes the expiry of key.
+func (s *Schema[M]) Persist(key string) (err error) {
+	s, end := s.trace("persist", key)
+	defer end(&err)
+
+	if s.StaleTtl > 0 || s.Recompute != nil {
+		return ErrTtlPolicy
+	}
+	store, ok := As[TtlStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	return store.Persist(s.ctx, s.generateKey(key))
+}
+
+// Touch resets the ttl of key to the schema Ttl, or the store default
+// without one.
+func (s *Schema[M]) Touch(key string) (err error) {
+	s, end := s.trace("touch", key)
+	defer end(&err)
+
+	if s.StaleTtl > 0 || s.Recompute != nil {
+		return ErrTtlPolicy
+	}
+	store, ok := As[TtlStore](s.Store)
+	if !ok {
+		return ErrNotSupported
+	}
+	if s.Ttl > 0 {
+		return store.Expire(s.ctx, s.generateKey(key), s.Ttl)
+	}
+	return store.Touch(s.ctx, s.generateKey(key))
+}
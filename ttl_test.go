@@ -0,0 +1,98 @@
+package cacher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinh-tinh/cacher/v2"
+)
+
+func Test_Ttl(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: cacher.NewInMemory(cacher.StoreOptions{
+			Ttl: 15 * time.Minute,
+		}),
+		Namespace: "users",
+	})
+
+	err := cache.Set("1", "John", cacher.StoreOptions{Ttl: 1 * time.Minute})
+	require.Nil(t, err)
+
+	ttl, err := cache.TTL("1")
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, time.Minute)
+	require.Greater(t, ttl, 58*time.Second)
+
+	err = cache.Expire("1", time.Hour)
+	require.Nil(t, err)
+	ttl, err = cache.TTL("1")
+	require.Nil(t, err)
+	require.Greater(t, ttl, 59*time.Minute)
+
+	err = cache.Persist("1")
+	require.Nil(t, err)
+	ttl, err = cache.TTL("1")
+	require.Nil(t, err)
+	require.Equal(t, cacher.NoTtl, ttl)
+
+	err = cache.Touch("1")
+	require.Nil(t, err)
+	ttl, err = cache.TTL("1")
+	require.Nil(t, err)
+	require.Greater(t, ttl, 14*time.Minute)
+
+	err = cache.ExpireAt("1", time.Now().Add(-time.Second))
+	require.Nil(t, err)
+	_, err = cache.Get("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+
+	_, err = cache.TTL("1")
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+	err = cache.Expire("1", time.Hour)
+	require.ErrorIs(t, err, cacher.ErrKeyNotFound)
+}
+
+func Test_TtlPolicy(t *testing.T) {
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store:    cacher.NewInMemory(cacher.StoreOptions{}),
+		Ttl:      time.Minute,
+		StaleTtl: 10 * time.Second,
+	})
+	require.Nil(t, cache.Set("1", "John"))
+
+	// the expiry in the value would keep applying
+	require.ErrorIs(t, cache.Expire("1", time.Hour), cacher.ErrTtlPolicy)
+	require.ErrorIs(t, cache.ExpireAt("1", time.Now().Add(time.Hour)), cacher.ErrTtlPolicy)
+	require.ErrorIs(t, cache.Persist("1"), cacher.ErrTtlPolicy)
+	require.ErrorIs(t, cache.Touch("1"), cacher.ErrTtlPolicy)
+
+	ttl, err := cache.TTL("1")
+	require.Nil(t, err)
+	require.Greater(t, ttl, time.Minute)
+}
+
+func Test_TouchTtl(t *testing.T) {
+	store := cacher.NewInMemory(cacher.StoreOptions{})
+	cache := cacher.NewSchema[string](cacher.Config{
+		Store: store,
+		Ttl:   time.Minute,
+	})
+
+	// the schema ttl wins over a store without one
+	require.Nil(t, cache.Set("1", "John"))
+	require.Nil(t, cache.Touch("1"))
+	data, err := cache.Get("1")
+	require.Nil(t, err)
+	require.Equal(t, "John", data)
+	ttl, err := cache.TTL("1")
+	require.Nil(t, err)
+	require.Greater(t, ttl, 58*time.Second)
+
+	// and a store without a ttl keeps touched keys
+	plain := cacher.NewSchema[string](cacher.Config{Store: store})
+	require.Nil(t, plain.Touch("1"))
+	ttl, err = plain.TTL("1")
+	require.Nil(t, err)
+	require.Equal(t, cacher.NoTtl, ttl)
+}
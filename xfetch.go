@@ -0,0 +1,27 @@
+package cacher
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// XFetch recomputes values probabilistically before they expire, so keys
+// written together do not all miss at the same moment. The chance of an
+// early expiry grows as the expiry nears and with how long the value took
+// to compute. Beta above 1 favours earlier recomputation, 0 means 1.
+type XFetch struct {
+	Beta float64
+}
+
+func (x *XFetch) expired(e entry, now time.Time) bool {
+	if e.expiry == 0 || e.delta <= 0 {
+		return false
+	}
+	beta := x.Beta
+	if beta <= 0 {
+		beta = 1
+	}
+	gap := -float64(e.delta) * beta * math.Log(1-rand.Float64())
+	return float64(now.UnixNano())+gap >= float64(e.expiry)
+}